@@ -0,0 +1,82 @@
+package feed
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func getSolidImage(c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestMakeCollage(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	green := color.RGBA{G: 255, A: 255}
+
+	collage := MakeCollage([]image.Image{getSolidImage(red), getSolidImage(blue), getSolidImage(green)}, 100)
+	assert.Equal(t, image.Rect(0, 0, 100, 100), collage.Bounds())
+
+	// 3 images are arranged in 2x2 grid
+	assert.Equal(t, red, collage.At(10, 10))
+	assert.Equal(t, blue, collage.At(60, 10))
+	assert.Equal(t, green, collage.At(10, 60))
+	assert.Equal(t, color.RGBA{}, collage.At(60, 60))
+
+	// No images
+	collage = MakeCollage(nil, 100)
+	assert.Equal(t, color.RGBA{}, collage.At(10, 10))
+}
+
+func TestSaveCollage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large.png" {
+			w.Header().Set("Content-Length", strconv.Itoa(maxAvatarSize+1))
+			_, _ = w.Write(make([]byte, maxAvatarSize+1))
+			return
+		}
+		if r.URL.Path != "/avatar.png" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var buf bytes.Buffer
+		assert.Nil(t, png.Encode(&buf, getSolidImage(color.RGBA{R: 255, A: 255})))
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	dir := filepath.Join(t.TempDir(), "new")
+
+	err := SaveCollage([]string{ts.URL + "/avatar.png", ts.URL + "/missing.png", ""}, dir, nil)
+	assert.Nil(t, err)
+
+	fh, err := os.Open(filepath.Join(dir, CollageFileName))
+	assert.Nil(t, err)
+	defer fh.Close()
+	img, err := png.Decode(fh)
+	assert.Nil(t, err)
+	assert.Equal(t, image.Rect(0, 0, collageSize, collageSize), img.Bounds())
+
+	// Too large images are not downloaded
+	_, err = downloadImage(nil, ts.URL+"/large.png")
+	assert.Equal(t, errTooLarge, err)
+
+	// No images to build collage from
+	err = SaveCollage([]string{ts.URL + "/missing.png", ts.URL + "/large.png"}, dir, nil)
+	assert.NotNil(t, err)
+}
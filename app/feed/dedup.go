@@ -0,0 +1,233 @@
+package feed
+
+import (
+	"fmt"
+	"github.com/gorilla/feeds"
+	"github.com/kulapard/tg2feed/app/parser"
+	"hash/fnv"
+	"html"
+	"math/bits"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DedupStrategy defines how duplicated items of the merged feed are detected
+type DedupStrategy string
+
+// Supported dedup strategies
+const (
+	// DedupForward detects forwards of the same message
+	DedupForward DedupStrategy = "forward"
+	// DedupMedia detects items with the same image or video URL
+	DedupMedia DedupStrategy = "media"
+	// DedupText detects items with similar text
+	DedupText DedupStrategy = "text"
+)
+
+// Items with fewer words are never considered similar
+const minSimilarWords = 8
+
+// Maximum SimHash distance of similar texts
+const maxSimilarDistance = 3
+
+var htmlTagRe = regexp.MustCompile("<[^>]*>")
+
+// ParseDedupStrategies parses comma separated list of dedup strategies
+func ParseDedupStrategies(str string) ([]DedupStrategy, error) {
+	var strategies []DedupStrategy
+	for _, name := range strings.Split(str, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		strategy := DedupStrategy(name)
+		switch strategy {
+		case DedupForward, DedupMedia, DedupText:
+			strategies = append(strategies, strategy)
+		default:
+			return nil, fmt.Errorf("unknown dedup strategy: %s", name)
+		}
+	}
+	return strategies, nil
+}
+
+// dedupItem is the merged item with the data used to detect duplicates
+type dedupItem struct {
+	item    *feeds.Item
+	channel *Source
+	links   []string
+	media   []string
+	hash    uint64
+	words   int
+}
+
+// isDuplicate reports whether the items have the same content according to the strategies
+func (d *dedupItem) isDuplicate(other *dedupItem, strategies []DedupStrategy) bool {
+	for _, strategy := range strategies {
+		switch strategy {
+		case DedupForward:
+			if intersects(d.links, other.links) {
+				return true
+			}
+		case DedupMedia:
+			if intersects(d.media, other.media) {
+				return true
+			}
+		case DedupText:
+			if d.words >= minSimilarWords && other.words >= minSimilarWords &&
+				bits.OnesCount64(d.hash^other.hash) <= maxSimilarDistance {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dedup removes items of the merged feed posted by several channels.
+// The oldest item is kept and gets references to all channels that posted it.
+func dedup(f *Feed, channels map[*feeds.Item]*Source, strategies []DedupStrategy, withSource bool) {
+	if len(strategies) == 0 {
+		return
+	}
+
+	// Oldest items go first to keep the original posts
+	items := make([]*feeds.Item, len(f.Items))
+	copy(items, f.Items)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Created.Before(items[j].Created)
+	})
+
+	var kept []*dedupItem
+	duplicates := make(map[*feeds.Item][]*Source)
+	removed := make(map[*feeds.Item]bool)
+	for _, item := range items {
+		d := newDedupItem(item, f.Posts[item], channels[item])
+		var original *dedupItem
+		for _, k := range kept {
+			// Only cross-posts are duplicates
+			if k.channel != d.channel && k.isDuplicate(d, strategies) {
+				original = k
+				break
+			}
+		}
+		if original == nil {
+			kept = append(kept, d)
+			continue
+		}
+		duplicates[original.item] = append(duplicates[original.item], d.channel)
+		removed[item] = true
+	}
+
+	var result []*feeds.Item
+	for _, item := range f.Items {
+		if removed[item] {
+			delete(f.Sources, item)
+			delete(f.Posts, item)
+			continue
+		}
+		if others := duplicates[item]; len(others) > 0 {
+			item.Description += "\n" + getAlsoPostedHTML(others)
+			if withSource {
+				f.Sources[item] = append(f.Sources[item], others...)
+			}
+		}
+		result = append(result, item)
+	}
+	f.Items = result
+}
+
+func newDedupItem(item *feeds.Item, post *parser.Post, channel *Source) *dedupItem {
+	d := &dedupItem{item: item, channel: channel}
+	if item.Link != nil {
+		d.links = append(d.links, canonicalLink(item.Link.Href))
+	}
+	if item.Enclosure != nil {
+		d.media = append(d.media, item.Enclosure.Url)
+	}
+	text := item.Description
+	if post != nil {
+		if post.ForwardedFrom != "" {
+			d.links = append(d.links, canonicalLink(post.ForwardedFrom))
+		}
+		if post.SourceMedia != nil {
+			// Mirrored media URLs are unique per channel, compare the original ones
+			d.media = append(d.media, post.SourceMedia...)
+		} else {
+			d.media = append(d.media, post.Images...)
+			d.media = append(d.media, post.Videos...)
+		}
+		text = post.Text
+	}
+	words := normalizeText(text)
+	d.words = len(words)
+	d.hash = simHash(words)
+	return d
+}
+
+// getAlsoPostedHTML returns the paragraph with the links to the channels that also posted the item
+func getAlsoPostedHTML(channels []*Source) string {
+	links := make([]string, len(channels))
+	for i, ch := range channels {
+		links[i] = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(ch.Link), html.EscapeString(ch.Title))
+	}
+	return "<p>Also posted in: " + strings.Join(links, ", ") + "</p>"
+}
+
+// canonicalLink returns the message link without scheme and web preview prefix, e.g. t.me/channel/1
+func canonicalLink(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	path := strings.TrimPrefix(u.Path, "/s/")
+	return u.Host + "/" + strings.Trim(path, "/")
+}
+
+// normalizeText returns lower-cased words of the HTML text without punctuation
+func normalizeText(text string) []string {
+	text = html.UnescapeString(htmlTagRe.ReplaceAllString(text, " "))
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// simHash returns SimHash of the word 3-shingles
+func simHash(words []string) uint64 {
+	const shingleSize = 3
+	var weights [64]int
+	for i := 0; i+shingleSize <= len(words) || (i == 0 && len(words) > 0); i++ {
+		end := min(i+shingleSize, len(words))
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(strings.Join(words[i:end], " ")))
+		sum := h.Sum64()
+		for b := 0; b < 64; b++ {
+			if sum&(1<<b) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+	var hash uint64
+	for b := 0; b < 64; b++ {
+		if weights[b] > 0 {
+			hash |= 1 << b
+		}
+	}
+	return hash
+}
+
+// intersects reports whether the slices have a common non-empty element
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x != "" && x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package feed
+
+import (
+	"fmt"
+	"github.com/gorilla/feeds"
+	"github.com/kulapard/tg2feed/app/parser"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseDedupStrategies(t *testing.T) {
+	strategies, err := ParseDedupStrategies("")
+	assert.Nil(t, err)
+	assert.Nil(t, strategies)
+
+	strategies, err = ParseDedupStrategies("forward, media,text")
+	assert.Nil(t, err)
+	assert.Equal(t, []DedupStrategy{DedupForward, DedupMedia, DedupText}, strategies)
+
+	_, err = ParseDedupStrategies("forward,unknown")
+	assert.Equal(t, fmt.Errorf("unknown dedup strategy: unknown"), err)
+}
+
+func getFeedsToDedup() []*Feed {
+	now := time.Now()
+	const announce = "<p>We are happy to announce the new version of our app with a lot of features!</p>"
+	page1 := &parser.Page{
+		Title: "Channel 1",
+		Link:  "https://t.me/s/channel1",
+		Posts: []*parser.Post{
+			{Title: "Original", Link: "https://t.me/s/channel1/1", Text: announce, Created: now.Add(-3 * time.Hour)},
+			{Title: "Photo", Link: "https://t.me/s/channel1/2", Text: "<p>Photo</p>", Created: now.Add(-2 * time.Hour),
+				Images: []string{"https://cdn.telegram.org/file/photo.jpg"}},
+		},
+	}
+	page2 := &parser.Page{
+		Title: "Channel 2",
+		Link:  "https://t.me/s/channel2",
+		Posts: []*parser.Post{
+			{Title: "Forward", Link: "https://t.me/s/channel2/10", Text: "<p>Forwarded</p>", Created: now.Add(-1 * time.Hour),
+				ForwardedFrom: "https://t.me/channel1/1"},
+			{Title: "Same photo", Link: "https://t.me/s/channel2/11", Text: "<p>Same photo</p>", Created: now.Add(-1 * time.Hour),
+				Images: []string{"https://cdn.telegram.org/file/photo.jpg"}},
+		},
+	}
+	page3 := &parser.Page{
+		Title: "Channel 3",
+		Link:  "https://t.me/s/channel3",
+		Posts: []*parser.Post{
+			{Title: "Repost", Link: "https://t.me/s/channel3/5", Text: "<p>We are happy to announce the new version of our app with a lot of features</p>",
+				Created: now.Add(-30 * time.Minute)},
+			{Title: "Other", Link: "https://t.me/s/channel3/6", Text: "<p>Completely different text about the weather in the city today</p>",
+				Created: now.Add(-20 * time.Minute)},
+		},
+	}
+	return []*Feed{GetFeed(page1, Options{}), GetFeed(page2, Options{}), GetFeed(page3, Options{})}
+}
+
+func getTitles(f *Feed) []string {
+	titles := make([]string, len(f.Items))
+	for i, item := range f.Items {
+		titles[i] = item.Title
+	}
+	return titles
+}
+
+func TestMerge_Dedup(t *testing.T) {
+	tbl := []struct {
+		strategies []DedupStrategy
+		titles     []string
+	}{
+		{nil, []string{"Other", "Repost", "Forward", "Same photo", "Photo", "Original"}},
+		{[]DedupStrategy{DedupForward}, []string{"Other", "Repost", "Same photo", "Photo", "Original"}},
+		{[]DedupStrategy{DedupMedia}, []string{"Other", "Repost", "Forward", "Photo", "Original"}},
+		{[]DedupStrategy{DedupText}, []string{"Other", "Forward", "Same photo", "Photo", "Original"}},
+		{[]DedupStrategy{DedupForward, DedupMedia, DedupText}, []string{"Other", "Photo", "Original"}},
+	}
+	for _, tb := range tbl {
+		feed := Merge(getFeedsToDedup(), MergeOptions{Dedup: tb.strategies})
+		assert.Equal(t, tb.titles, getTitles(feed))
+	}
+}
+
+func TestMerge_DedupReferences(t *testing.T) {
+	feed := Merge(getFeedsToDedup(), MergeOptions{Dedup: []DedupStrategy{DedupForward, DedupText}, Source: true})
+	assert.Equal(t, []string{"Other", "Same photo", "Photo", "Original"}, getTitles(feed))
+
+	original := feed.Items[3]
+	assert.Equal(t, "<p>We are happy to announce the new version of our app with a lot of features!</p>\n"+
+		`<p>Also posted in: <a href="https://t.me/s/channel2">Channel 2</a>, <a href="https://t.me/s/channel3">Channel 3</a></p>`,
+		original.Description)
+	assert.Equal(t, []*Source{
+		{Title: "Channel 1", Link: "https://t.me/s/channel1"},
+		{Title: "Channel 2", Link: "https://t.me/s/channel2"},
+		{Title: "Channel 3", Link: "https://t.me/s/channel3"},
+	}, feed.Sources[original])
+
+	// Removed items are not tracked anymore
+	assert.Equal(t, 4, len(feed.Sources))
+	assert.Equal(t, 4, len(feed.Posts))
+}
+
+func TestMerge_DedupSameChannel(t *testing.T) {
+	// Items of the same channel are never duplicates
+	feed1 := &Feed{Feed: &feeds.Feed{
+		Title: "Channel 1",
+		Link:  &feeds.Link{Href: "https://t.me/s/channel1"},
+		Items: []*feeds.Item{
+			{Title: "Post 1", Enclosure: &feeds.Enclosure{Url: "https://cdn.telegram.org/file/photo.jpg"}},
+			{Title: "Post 2", Enclosure: &feeds.Enclosure{Url: "https://cdn.telegram.org/file/photo.jpg"}},
+		},
+	}}
+	feed := Merge([]*Feed{feed1}, MergeOptions{Dedup: []DedupStrategy{DedupMedia}})
+	assert.Equal(t, 2, len(feed.Items))
+}
+
+func TestMerge_DedupMirroredMedia(t *testing.T) {
+	// Mirrored media has the different URLs in every channel, the original ones are compared
+	getPage := func(channel string) *parser.Page {
+		return &parser.Page{
+			Title: channel,
+			Link:  "https://t.me/" + channel,
+			Posts: []*parser.Post{{Title: "Photo of " + channel, Link: "https://t.me/s/" + channel + "/1", MessageID: 1,
+				Images:      []string{"https://example.com/media/" + channel + "/1-e36a209f.jpg"},
+				SourceMedia: []string{"https://cdn.telegram.org/file/photo.jpg"}}},
+		}
+	}
+	channelFeeds := []*Feed{GetFeed(getPage("channel1"), Options{}), GetFeed(getPage("channel2"), Options{})}
+	feed := Merge(channelFeeds, MergeOptions{Dedup: []DedupStrategy{DedupMedia}})
+	assert.Equal(t, 1, len(feed.Items))
+}
+
+func TestCanonicalLink(t *testing.T) {
+	assert.Equal(t, "t.me/channel/1", canonicalLink("https://t.me/s/channel/1"))
+	assert.Equal(t, "t.me/channel/1", canonicalLink("https://t.me/channel/1/"))
+	assert.Equal(t, "t.me/channel/1", canonicalLink("https://t.me/channel/1?single"))
+}
+
+func TestSimHash(t *testing.T) {
+	words1 := normalizeText("<p>We are happy to announce the new version of our app with a lot of features!</p>")
+	words2 := normalizeText("We are HAPPY to announce the new version of our app, with a lot of features.")
+	assert.Equal(t, simHash(words1), simHash(words2))
+	assert.Equal(t, uint64(0), simHash(nil))
+	assert.NotEqual(t, uint64(0), simHash([]string{"one"}))
+}
@@ -6,31 +6,143 @@ import (
 	"encoding/hex"
 	"github.com/gorilla/feeds"
 	"github.com/kulapard/tg2feed/app/parser"
+	"html"
+	htmltemplate "html/template"
 	"log"
+	"mime"
+	"net/url"
 	"os"
+	"path"
 	"strings"
+	"text/template"
 	"time"
 )
 
+// Options defines how feed items are built
+type Options struct {
+	// TitleTemplate renders the item title, post title is used if nil
+	TitleTemplate *template.Template
+	// BodyTemplate renders the item body, post text is used if nil
+	BodyTemplate *htmltemplate.Template
+	// GUID strategy of the items, link hash if not set
+	GUID GUIDStrategy
+	// PreviousGUIDs are the GUIDs of already published items by post link, they are kept as is
+	PreviousGUIDs map[string]GUID
+}
+
+// Feed is the feed with the data not supported by gorilla/feeds
+type Feed struct {
+	*feeds.Feed
+	// Language is the feed language code, e.g. "en"
+	Language string
+	// Posts maps items to the posts they are built from
+	Posts map[*feeds.Item]*parser.Post
+	// Sources maps merged items to the channels they come from
+	Sources map[*feeds.Item][]*Source
+	// PermaLinks are the items with GUID being the item link
+	PermaLinks map[*feeds.Item]bool
+	// Counters and Verified badge of the channel, rendered as feed extensions
+	Counters parser.Counters
+	Verified bool
+}
+
+// Source is the channel the feed item comes from
+type Source struct {
+	Title    string
+	Link     string
+	ImageURL string
+}
+
+// MergeOptions defines how feeds are merged
+type MergeOptions struct {
+	// TitlePrefix prepends "[Channel]" to the item titles
+	TitlePrefix bool
+	// Source adds the channel to the item: RSS <source>, Atom <source> and JSON Feed authors
+	Source bool
+	// Dedup strategies to detect items posted by several channels, no dedup if empty
+	Dedup []DedupStrategy
+
+	// Merged feed metadata, defaults are used for empty title, description and link
+	Title       string
+	Description string
+	Link        string
+	ImageURL    string
+	Language    string
+	Copyright   string
+}
+
+const defaultMergedTitle = "Telegram Feed"
+const defaultMergedLink = "https://github.com/kulapard/tg2feed"
+
 // Merge feeds
-func Merge(fs []*feeds.Feed) *feeds.Feed {
-	// Extract channels links
-	links := make([]string, len(fs))
-	for i, feed := range fs {
-		links[i] = feed.Link.Href
-	}
-	linksStr := strings.Join(links, ", ")
-	mergedFeed := &feeds.Feed{
-		Title:       "Telegram Feed",
-		Description: "Channels: " + linksStr,
-		Link:        &feeds.Link{Href: "https://github.com/kulapard/tg2feed"},
-		Created:     time.Now(),
+func Merge(fs []*Feed, opts MergeOptions) *Feed {
+	title := opts.Title
+	if title == "" {
+		title = defaultMergedTitle
+	}
+	description := opts.Description
+	if description == "" {
+		// Extract channels links
+		links := make([]string, len(fs))
+		for i, feed := range fs {
+			links[i] = feed.Link.Href
+		}
+		description = "Channels: " + strings.Join(links, ", ")
+	}
+	link := opts.Link
+	if link == "" {
+		link = defaultMergedLink
+	}
+	mergedFeed := &Feed{
+		Feed: &feeds.Feed{
+			Title:       title,
+			Description: description,
+			Link:        &feeds.Link{Href: link},
+			Copyright:   opts.Copyright,
+			Created:     time.Now(),
+		},
+		Language:   opts.Language,
+		Posts:      make(map[*feeds.Item]*parser.Post),
+		Sources:    make(map[*feeds.Item][]*Source),
+		PermaLinks: make(map[*feeds.Item]bool),
+	}
+	if opts.ImageURL != "" {
+		mergedFeed.Image = &feeds.Image{Url: opts.ImageURL, Title: title, Link: link}
 	}
 	// Merge items
+	channels := make(map[*feeds.Item]*Source)
 	for _, feed := range fs {
-		mergedFeed.Items = append(mergedFeed.Items, feed.Items...)
+		src := getSource(feed)
+		for _, origItem := range feed.Items {
+			// Copy the item to keep the original feed untouched
+			item := *origItem
+			if opts.TitlePrefix {
+				item.Title = "[" + src.Title + "] " + item.Title
+			}
+			if opts.Source {
+				mergedFeed.Sources[&item] = []*Source{src}
+			}
+			if post := feed.Posts[origItem]; post != nil {
+				mergedFeed.Posts[&item] = post
+			}
+			if feed.PermaLinks[origItem] {
+				mergedFeed.PermaLinks[&item] = true
+			}
+			channels[&item] = src
+			mergedFeed.Add(&item)
+			// Merged feed is updated when the newest item is
+			if item.Created.After(mergedFeed.Updated) {
+				mergedFeed.Updated = item.Created
+			}
+			if item.Updated.After(mergedFeed.Updated) {
+				mergedFeed.Updated = item.Updated
+			}
+		}
 	}
 
+	// Remove items posted by several channels
+	dedup(mergedFeed, channels, opts.Dedup, opts.Source)
+
 	// Sort items by created date
 	sorFunc := func(a, b *feeds.Item) bool {
 		return a.Created.After(b.Created)
@@ -40,16 +152,34 @@ func Merge(fs []*feeds.Feed) *feeds.Feed {
 	return mergedFeed
 }
 
+// getSource returns the source channel of the feed
+func getSource(f *Feed) *Source {
+	src := &Source{Title: f.Title}
+	if f.Link != nil {
+		src.Link = f.Link.Href
+	}
+	if f.Image != nil {
+		src.ImageURL = f.Image.Url
+	}
+	return src
+}
+
 // GetFeed returns RSS feed for Telegram channel web page
-func GetFeed(page *parser.Page) *feeds.Feed {
+func GetFeed(page *parser.Page, opts Options) *Feed {
 	now := time.Now()
-	feed := &feeds.Feed{
-		Title:       page.Title,
-		Link:        &feeds.Link{Href: page.Link},
-		Description: page.Description,
-		Created:     now,
-		Updated:     now,
-		Author:      &feeds.Author{Name: page.Title},
+	feed := &Feed{
+		Feed: &feeds.Feed{
+			Title:       page.Title,
+			Link:        &feeds.Link{Href: page.Link},
+			Description: page.Description,
+			Created:     now,
+			Updated:     now,
+			Author:      &feeds.Author{Name: page.Title},
+		},
+		Posts:      make(map[*feeds.Item]*parser.Post),
+		PermaLinks: make(map[*feeds.Item]bool),
+		Counters:   page.Counters,
+		Verified:   page.Verified,
 	}
 
 	if page.ImageURL != "" {
@@ -74,18 +204,42 @@ func GetFeed(page *parser.Page) *feeds.Feed {
 				Length: "0", //todo: get length
 				Type:   "video/mp4",
 			}
+		} else if post.Sticker != nil && post.Sticker.PreviewURL != "" {
+			enclosure = &feeds.Enclosure{
+				Url:    post.Sticker.PreviewURL,
+				Length: "0", //todo: get length
+				Type:   getImageType(post.Sticker.PreviewURL),
+			}
 		}
+		data := newItemData(page, post)
+		title, err := renderTitle(opts.TitleTemplate, data)
+		if err != nil {
+			log.Printf("[ERROR] failed to render title for %s: %v, fallback with post title", post.Link, err)
+		}
+		body, err := renderBody(opts.BodyTemplate, data)
+		if err != nil {
+			log.Printf("[ERROR] failed to render body for %s: %v, fallback with post text", post.Link, err)
+		}
+		if body == "" && post.Sticker != nil && post.Sticker.PreviewURL != "" {
+			// Sticker post has no text, show the sticker image instead
+			body = `<img src="` + html.EscapeString(post.Sticker.PreviewURL) + `" alt="Sticker"/>`
+		}
+		guid := getPostGUID(post, opts.GUID, opts.PreviousGUIDs)
 		feed.Items[i] = &feeds.Item{
-			Id:          GetGUID(post.Link),
-			Title:       post.Title,
+			Id:          guid.ID,
+			Title:       title,
 			Link:        &feeds.Link{Href: post.Link},
-			Description: post.Text,
+			Description: body,
 			Author:      &feeds.Author{Name: page.Title},
 			Created:     post.Created,
 		}
 		if enclosure != nil {
 			feed.Items[i].Enclosure = enclosure
 		}
+		feed.Posts[feed.Items[i]] = post
+		if guid.IsPermaLink {
+			feed.PermaLinks[feed.Items[i]] = true
+		}
 	}
 
 	// Sort items by created date
@@ -97,6 +251,16 @@ func GetFeed(page *parser.Page) *feeds.Feed {
 	return feed
 }
 
+// getImageType returns MIME type of the image by its URL extension, image/webp if unknown
+func getImageType(imageURL string) string {
+	if u, err := url.Parse(imageURL); err == nil {
+		if mimeType := mime.TypeByExtension(path.Ext(u.Path)); strings.HasPrefix(mimeType, "image/") {
+			return mimeType
+		}
+	}
+	return "image/webp"
+}
+
 // GetGUID returns the GUID for the specified string
 func GetGUID(str string) string {
 	hash := sha256.Sum256([]byte(str))
@@ -117,27 +281,27 @@ func save(fileName, content string) error {
 	return nil
 }
 
-func saveToRSS(feed *feeds.Feed, dir string) error {
+func saveToRSS(feed *Feed, dir string) error {
 	fname := dir + "/rss.xml"
-	content, err := feed.ToRss()
+	content, err := toRSS(feed)
 	if err != nil {
 		return err
 	}
 	return save(fname, content)
 }
 
-func saveToAtom(feed *feeds.Feed, dir string) error {
+func saveToAtom(feed *Feed, dir string) error {
 	fname := dir + "/atom.xml"
-	content, err := feed.ToAtom()
+	content, err := toAtom(feed)
 	if err != nil {
 		return err
 	}
 	return save(fname, content)
 }
 
-func saveToJSON(feed *feeds.Feed, dir string) error {
+func saveToJSON(feed *Feed, dir string) error {
 	fname := dir + "/feed.json"
-	content, err := feed.ToJSON()
+	content, err := toJSON(feed)
 	if err != nil {
 		return err
 	}
@@ -145,7 +309,7 @@ func saveToJSON(feed *feeds.Feed, dir string) error {
 }
 
 // SaveToFile saves RSS feed to file
-func SaveToFile(f *feeds.Feed, dir string, formats []string) error {
+func SaveToFile(f *Feed, dir string, formats []string) error {
 	// Check id directory exists
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		// Create directory recursively
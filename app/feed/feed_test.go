@@ -25,7 +25,7 @@ func TestGetFeed(t *testing.T) {
 			{Title: "Post 3", Link: "https://t.me/s/telegram/3", Text: "Post 3 text", Created: now.Add(time.Hour * -3), Videos: []string{"https://telegram.org/video/3.mp4"}},
 		},
 	}
-	feed := GetFeed(page)
+	feed := GetFeed(page, Options{})
 	assert.NotNil(t, feed)
 	assert.Equal(t, "Channel Title", feed.Title)
 	assert.Equal(t, "Channel Title", feed.Author.Name)
@@ -54,6 +54,27 @@ func TestGetFeed(t *testing.T) {
 	assert.Equal(t, "https://telegram.org/video/3.mp4", item3.Enclosure.Url)
 }
 
+func TestGetFeed_Sticker(t *testing.T) {
+	page := &parser.Page{
+		Title: "Channel Title",
+		Link:  "https://t.me/s/telegram",
+		Posts: []*parser.Post{
+			{Title: "Sticker", Link: "https://t.me/s/telegram/1", Created: time.Now(),
+				Sticker: &parser.Sticker{URL: "https://telegram.org/sticker.tgs", PreviewURL: "https://telegram.org/sticker.png", Animated: true}},
+		},
+	}
+	feed := GetFeed(page, Options{})
+	assert.Equal(t, 1, len(feed.Items))
+	assert.Equal(t, `<img src="https://telegram.org/sticker.png" alt="Sticker"/>`, feed.Items[0].Description)
+	assert.Equal(t, &feeds.Enclosure{Url: "https://telegram.org/sticker.png", Length: "0", Type: "image/png"}, feed.Items[0].Enclosure)
+}
+
+func TestGetImageType(t *testing.T) {
+	assert.Equal(t, "image/png", getImageType("https://telegram.org/sticker.png?size=1"))
+	assert.Equal(t, "image/webp", getImageType("https://telegram.org/sticker.webp"))
+	assert.Equal(t, "image/webp", getImageType("https://telegram.org/file/sticker"))
+}
+
 func TestGetGUID(t *testing.T) {
 	tbl := []struct {
 		inp string
@@ -72,31 +93,31 @@ func TestGetGUID(t *testing.T) {
 }
 
 func TestMerge(t *testing.T) {
-	feed1 := &feeds.Feed{
+	feed1 := &Feed{Feed: &feeds.Feed{
 		Title: "Channel 1",
 		Link:  &feeds.Link{Href: "https://t.me/s/telegram1"},
 		Items: []*feeds.Item{
 			{Title: "Post 1", Link: &feeds.Link{Href: "https://t.me/s/telegram1/1"}},
 			{Title: "Post 2", Link: &feeds.Link{Href: "https://t.me/s/telegram1/2"}},
 		},
-	}
-	feed2 := &feeds.Feed{
+	}}
+	feed2 := &Feed{Feed: &feeds.Feed{
 		Title: "Channel 2",
 		Link:  &feeds.Link{Href: "https://t.me/s/telegram2"},
 		Items: []*feeds.Item{
 			{Title: "Post 3", Link: &feeds.Link{Href: "https://t.me/s/telegram2/3"}},
 			{Title: "Post 4", Link: &feeds.Link{Href: "https://t.me/s/telegram2/4"}},
 		},
-	}
-	feed3 := &feeds.Feed{
+	}}
+	feed3 := &Feed{Feed: &feeds.Feed{
 		Title: "Channel 3",
 		Link:  &feeds.Link{Href: "https://t.me/s/telegram3"},
 		Items: []*feeds.Item{
 			{Title: "Post 5", Link: &feeds.Link{Href: "https://t.me/s/telegram3/5"}},
 			{Title: "Post 6", Link: &feeds.Link{Href: "https://t.me/s/telegram3/6"}},
 		},
-	}
-	feed := Merge([]*feeds.Feed{feed1, feed2, feed3})
+	}}
+	feed := Merge([]*Feed{feed1, feed2, feed3}, MergeOptions{})
 	assert.NotNil(t, feed)
 	assert.Equal(t, "Telegram Feed", feed.Title)
 	assert.Equal(t, "Channels: https://t.me/s/telegram1, https://t.me/s/telegram2, https://t.me/s/telegram3", feed.Description)
@@ -104,7 +125,7 @@ func TestMerge(t *testing.T) {
 }
 
 func TestMerge_Empty(t *testing.T) {
-	feed := Merge([]*feeds.Feed{})
+	feed := Merge([]*Feed{}, MergeOptions{})
 	assert.NotNil(t, feed)
 	assert.Equal(t, "Telegram Feed", feed.Title)
 	assert.Equal(t, "Channels: ", feed.Description)
@@ -113,31 +134,31 @@ func TestMerge_Empty(t *testing.T) {
 
 func TestMerge_Sort(t *testing.T) {
 	now := time.Now()
-	feed1 := &feeds.Feed{
+	feed1 := &Feed{Feed: &feeds.Feed{
 		Title: "Channel 1",
 		Link:  &feeds.Link{Href: "https://t.me/s/telegram1"},
 		Items: []*feeds.Item{
 			{Title: "Post 2", Link: &feeds.Link{Href: "https://t.me/s/telegram1/2"}, Created: now.Add(time.Hour * -2)},
 			{Title: "Post 1", Link: &feeds.Link{Href: "https://t.me/s/telegram1/1"}, Created: now.Add(time.Hour * -1)},
 		},
-	}
-	feed2 := &feeds.Feed{
+	}}
+	feed2 := &Feed{Feed: &feeds.Feed{
 		Title: "Channel 2",
 		Link:  &feeds.Link{Href: "https://t.me/s/telegram2"},
 		Items: []*feeds.Item{
 			{Title: "Post 4", Link: &feeds.Link{Href: "https://t.me/s/telegram2/4"}, Created: now.Add(time.Hour * -4)},
 			{Title: "Post 3", Link: &feeds.Link{Href: "https://t.me/s/telegram2/3"}, Created: now.Add(time.Hour * -3)},
 		},
-	}
-	feed3 := &feeds.Feed{
+	}}
+	feed3 := &Feed{Feed: &feeds.Feed{
 		Title: "Channel 3",
 		Link:  &feeds.Link{Href: "https://t.me/s/telegram3"},
 		Items: []*feeds.Item{
 			{Title: "Post 6", Link: &feeds.Link{Href: "https://t.me/s/telegram3/6"}, Created: now.Add(time.Hour * -6)},
 			{Title: "Post 5", Link: &feeds.Link{Href: "https://t.me/s/telegram3/5"}, Created: now.Add(time.Hour * -4)},
 		},
-	}
-	feed := Merge([]*feeds.Feed{feed1, feed2, feed3})
+	}}
+	feed := Merge([]*Feed{feed1, feed2, feed3}, MergeOptions{})
 	assert.NotNil(t, feed)
 	assert.Equal(t, "Telegram Feed", feed.Title)
 	assert.Equal(t, "Channels: https://t.me/s/telegram1, https://t.me/s/telegram2, https://t.me/s/telegram3", feed.Description)
@@ -151,15 +172,15 @@ func TestMerge_Sort(t *testing.T) {
 	assert.Equal(t, "Post 6", feed.Items[5].Title)
 }
 
-func getFeedToSave() *feeds.Feed {
-	feed := &feeds.Feed{
+func getFeedToSave() *Feed {
+	feed := &Feed{Feed: &feeds.Feed{
 		Title: "Channel 1",
 		Link:  &feeds.Link{Href: "https://t.me/s/telegram1"},
 		Items: []*feeds.Item{
 			{Title: "Post 1", Link: &feeds.Link{Href: "https://t.me/s/telegram1/1"}},
 			{Title: "Post 2", Link: &feeds.Link{Href: "https://t.me/s/telegram1/2"}},
 		},
-	}
+	}}
 	return feed
 }
 
@@ -234,3 +255,72 @@ func TestSaveToFile(t *testing.T) {
 
 	}
 }
+
+func TestMerge_Source(t *testing.T) {
+	feed1 := &Feed{Feed: &feeds.Feed{
+		Title: "Channel 1",
+		Link:  &feeds.Link{Href: "https://t.me/s/telegram1"},
+		Image: &feeds.Image{Url: "https://telegram.org/img/1.png"},
+		Items: []*feeds.Item{
+			{Title: "Post 1", Link: &feeds.Link{Href: "https://t.me/s/telegram1/1"}},
+		},
+	}}
+	feed2 := &Feed{Feed: &feeds.Feed{
+		Title: "Channel 2",
+		Link:  &feeds.Link{Href: "https://t.me/s/telegram2"},
+		Items: []*feeds.Item{
+			{Title: "Post 2", Link: &feeds.Link{Href: "https://t.me/s/telegram2/2"}},
+		},
+	}}
+
+	// No markers by default
+	feed := Merge([]*Feed{feed1, feed2}, MergeOptions{})
+	assert.Equal(t, "Post 1", feed.Items[0].Title)
+	assert.Equal(t, 0, len(feed.Sources))
+
+	feed = Merge([]*Feed{feed1, feed2}, MergeOptions{TitlePrefix: true, Source: true})
+	assert.Equal(t, "[Channel 1] Post 1", feed.Items[0].Title)
+	assert.Equal(t, "[Channel 2] Post 2", feed.Items[1].Title)
+	assert.Equal(t, []*Source{{Title: "Channel 1", Link: "https://t.me/s/telegram1", ImageURL: "https://telegram.org/img/1.png"}}, feed.Sources[feed.Items[0]])
+	assert.Equal(t, []*Source{{Title: "Channel 2", Link: "https://t.me/s/telegram2"}}, feed.Sources[feed.Items[1]])
+
+	// Original feeds stay untouched
+	assert.Equal(t, "Post 1", feed1.Items[0].Title)
+	assert.Equal(t, "Post 2", feed2.Items[0].Title)
+}
+
+func TestMerge_Metadata(t *testing.T) {
+	now := time.Now()
+	feed1 := &Feed{Feed: &feeds.Feed{
+		Title: "Channel 1",
+		Link:  &feeds.Link{Href: "https://t.me/s/telegram1"},
+		Items: []*feeds.Item{
+			{Title: "Post 1", Created: now.Add(time.Hour * -2)},
+			{Title: "Post 2", Created: now.Add(time.Hour * -3), Updated: now.Add(time.Hour * -1)},
+		},
+	}}
+	opts := MergeOptions{
+		Title:       "My Feed",
+		Description: "My channels",
+		Link:        "https://example.com",
+		ImageURL:    "https://example.com/logo.png",
+		Language:    "en",
+		Copyright:   "CC BY 4.0",
+	}
+	feed := Merge([]*Feed{feed1}, opts)
+	assert.Equal(t, "My Feed", feed.Title)
+	assert.Equal(t, "My channels", feed.Description)
+	assert.Equal(t, "https://example.com", feed.Link.Href)
+	assert.Equal(t, &feeds.Image{Url: "https://example.com/logo.png", Title: "My Feed", Link: "https://example.com"}, feed.Image)
+	assert.Equal(t, "en", feed.Language)
+	assert.Equal(t, "CC BY 4.0", feed.Copyright)
+	assert.Equal(t, now.Add(time.Hour*-1), feed.Updated)
+
+	// Defaults
+	feed = Merge([]*Feed{feed1}, MergeOptions{})
+	assert.Equal(t, "Telegram Feed", feed.Title)
+	assert.Equal(t, "Channels: https://t.me/s/telegram1", feed.Description)
+	assert.Equal(t, "https://github.com/kulapard/tg2feed", feed.Link.Href)
+	assert.Nil(t, feed.Image)
+	assert.Equal(t, "", feed.Language)
+}
@@ -0,0 +1,83 @@
+package feed
+
+import (
+	"bufio"
+	"flag"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/kulapard/tg2feed/app/parser"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Run "go test ./app/parser ./app/feed -update" to regenerate golden files after intended changes
+var update = flag.Bool("update", false, "update golden files")
+
+// goldenPages are the saved channel web preview pages of the parser corpus
+const goldenPages = "../parser/testdata/pages"
+
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(goldenPages, "*.html"))
+	assert.Nil(t, err)
+	recorded, err := filepath.Glob(filepath.Join(goldenPages, "*.http"))
+	assert.Nil(t, err)
+	files = append(files, recorded...)
+	assert.NotEmpty(t, files)
+
+	outputs := []struct {
+		ext    string
+		render func(*Feed) (string, error)
+	}{
+		{".rss.xml", toRSS},
+		{".atom.xml", toAtom},
+		{".json", toJSON},
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		t.Run(name, func(t *testing.T) {
+			doc, err := readGoldenPage(file)
+			assert.Nil(t, err)
+
+			f := GetFeed(parser.GetPage(doc, parser.Options{}), Options{})
+			// Feed build time is the only value not coming from the page
+			f.Created = time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+			f.Updated = f.Created
+
+			for _, output := range outputs {
+				content, err := output.render(f)
+				assert.Nil(t, err)
+				content += "\n"
+
+				goldenFile := filepath.Join("testdata", "golden", name+output.ext)
+				if *update {
+					assert.Nil(t, os.WriteFile(goldenFile, []byte(content), 0o600))
+				}
+				expected, err := os.ReadFile(goldenFile) //nolint:gosec // test data
+				assert.Nil(t, err)
+				assert.Equal(t, string(expected), content, goldenFile)
+			}
+		})
+	}
+}
+
+// readGoldenPage reads the page saved from the browser (.html) or recorded with --record-dir (.http)
+func readGoldenPage(file string) (*goquery.Document, error) {
+	fh, err := os.Open(file) //nolint:gosec // test data
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() // nolint
+	if filepath.Ext(file) != ".http" {
+		return goquery.NewDocumentFromReader(fh)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(fh), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close() // nolint
+	return goquery.NewDocumentFromReader(res.Body)
+}
@@ -0,0 +1,151 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/kulapard/tg2feed/app/parser"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GUIDStrategy defines how the item GUID is built
+type GUIDStrategy string
+
+// Supported GUID strategies
+const (
+	// GUIDHash is SHA-256 hex of the post link
+	GUIDHash GUIDStrategy = "hash"
+	// GUIDTag is tag: URI of the channel and message ID, e.g. tag:t.me,2013:telegram/1
+	GUIDTag GUIDStrategy = "tag"
+	// GUIDPermalink is the post link, marked as permalink in RSS
+	GUIDPermalink GUIDStrategy = "permalink"
+)
+
+// Authority and date of tag: URIs, see RFC 4151
+const guidTagPrefix = "tag:t.me,2013:"
+
+// GUID is the item GUID
+type GUID struct {
+	ID          string
+	IsPermaLink bool
+}
+
+// ParseGUIDStrategy parses the GUID strategy name
+func ParseGUIDStrategy(str string) (GUIDStrategy, error) {
+	strategy := GUIDStrategy(strings.TrimSpace(str))
+	switch strategy {
+	case "":
+		return GUIDHash, nil
+	case GUIDHash, GUIDTag, GUIDPermalink:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown GUID strategy: %s", str)
+}
+
+// getPostGUID returns the post GUID built by the strategy, previous GUID of the post is kept if known
+func getPostGUID(post *parser.Post, strategy GUIDStrategy, previous map[string]GUID) GUID {
+	if guid, ok := previous[canonicalLink(post.Link)]; ok {
+		return guid
+	}
+	switch strategy {
+	case GUIDTag:
+		if post.Channel != "" && post.MessageID > 0 {
+			return GUID{ID: guidTagPrefix + post.Channel + "/" + strconv.Itoa(post.MessageID)}
+		}
+		log.Printf("[ERROR] post %s has no message ID, fallback with link hash GUID", post.Link)
+	case GUIDPermalink:
+		return GUID{ID: post.Link, IsPermaLink: true}
+	}
+	return GUID{ID: GetGUID(post.Link)}
+}
+
+// previousRSS is the part of RSS feed with item GUIDs
+type previousRSS struct {
+	Items []struct {
+		Link string `xml:"link"`
+		GUID struct {
+			ID          string `xml:",chardata"`
+			IsPermaLink string `xml:"isPermaLink,attr"`
+		} `xml:"guid"`
+	} `xml:"channel>item"`
+}
+
+// previousAtom is the part of Atom feed with entry IDs
+type previousAtom struct {
+	Entries []struct {
+		ID    string `xml:"id"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// previousJSON is the part of JSON Feed with item IDs
+type previousJSON struct {
+	Items []struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	} `json:"items"`
+}
+
+// LoadGUIDs reads the feed files previously saved to the directory and returns item GUIDs by post link.
+// Missing and broken files are skipped.
+func LoadGUIDs(dir string) map[string]GUID {
+	guids := make(map[string]GUID)
+	add := func(link, id string, isPermaLink bool) {
+		if link == "" || id == "" {
+			return
+		}
+		key := canonicalLink(link)
+		if _, ok := guids[key]; !ok {
+			guids[key] = GUID{ID: id, IsPermaLink: isPermaLink}
+		}
+	}
+
+	// RSS goes first as the only format with isPermaLink
+	var rss previousRSS
+	if loadPrevious(filepath.Join(dir, "rss.xml"), func(data []byte) error { return xml.Unmarshal(data, &rss) }) {
+		for _, item := range rss.Items {
+			add(item.Link, strings.TrimSpace(item.GUID.ID), item.GUID.IsPermaLink == "true")
+		}
+	}
+	var atom previousAtom
+	if loadPrevious(filepath.Join(dir, "atom.xml"), func(data []byte) error { return xml.Unmarshal(data, &atom) }) {
+		for _, entry := range atom.Entries {
+			for _, link := range entry.Links {
+				if link.Rel == "" || link.Rel == "alternate" {
+					add(link.Href, strings.TrimSpace(entry.ID), false)
+					break
+				}
+			}
+		}
+	}
+	var jsonFeed previousJSON
+	if loadPrevious(filepath.Join(dir, "feed.json"), func(data []byte) error { return json.Unmarshal(data, &jsonFeed) }) {
+		for _, item := range jsonFeed.Items {
+			add(item.URL, item.ID, false)
+		}
+	}
+	return guids
+}
+
+// loadPrevious reads and decodes the file, reports whether it's loaded
+func loadPrevious(fileName string, decode func(data []byte) error) bool {
+	data, err := os.ReadFile(fileName) //nolint:gosec // tolerable security risk
+	if os.IsNotExist(err) {
+		return false
+	}
+	if err == nil {
+		err = decode(data)
+	}
+	if err != nil {
+		log.Printf("[ERROR] failed to load previous GUIDs from %s: %v, skipping", fileName, err)
+		return false
+	}
+	return true
+}
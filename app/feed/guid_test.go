@@ -0,0 +1,94 @@
+package feed
+
+import (
+	"fmt"
+	"github.com/kulapard/tg2feed/app/parser"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseGUIDStrategy(t *testing.T) {
+	tbl := []struct {
+		inp string
+		out GUIDStrategy
+		err error
+	}{
+		{"", GUIDHash, nil},
+		{"hash", GUIDHash, nil},
+		{" tag ", GUIDTag, nil},
+		{"permalink", GUIDPermalink, nil},
+		{"unknown", "", fmt.Errorf("unknown GUID strategy: unknown")},
+	}
+	for _, tb := range tbl {
+		strategy, err := ParseGUIDStrategy(tb.inp)
+		assert.Equal(t, tb.err, err)
+		assert.Equal(t, tb.out, strategy)
+	}
+}
+
+func TestGetPostGUID(t *testing.T) {
+	post := &parser.Post{Link: "https://t.me/s/telegram/1", Channel: "telegram", MessageID: 1}
+	noID := &parser.Post{Link: "https://t.me/s/telegram/2"}
+	previous := map[string]GUID{"t.me/telegram/3": {ID: "old"}}
+
+	tbl := []struct {
+		post     *parser.Post
+		strategy GUIDStrategy
+		out      GUID
+	}{
+		{post, "", GUID{ID: GetGUID(post.Link)}},
+		{post, GUIDHash, GUID{ID: GetGUID(post.Link)}},
+		{post, GUIDTag, GUID{ID: "tag:t.me,2013:telegram/1"}},
+		{noID, GUIDTag, GUID{ID: GetGUID(noID.Link)}},
+		{post, GUIDPermalink, GUID{ID: "https://t.me/s/telegram/1", IsPermaLink: true}},
+		{&parser.Post{Link: "https://t.me/telegram/3"}, GUIDPermalink, GUID{ID: "old"}},
+	}
+	for _, tb := range tbl {
+		assert.Equal(t, tb.out, getPostGUID(tb.post, tb.strategy, previous))
+	}
+}
+
+func TestLoadGUIDs(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, map[string]GUID{}, LoadGUIDs(dir))
+
+	page := &parser.Page{
+		Title: "Channel Title",
+		Link:  "https://t.me/s/telegram",
+		Posts: []*parser.Post{
+			{Title: "Post 1", Link: "https://t.me/s/telegram/1", Channel: "telegram", MessageID: 1, Created: time.Now()},
+			{Title: "Post 2", Link: "https://t.me/s/telegram/2", Channel: "telegram", MessageID: 2, Created: time.Now()},
+		},
+	}
+
+	// GUIDs are loaded from each format
+	for _, format := range []string{"rss", "atom", "json"} {
+		formatDir := filepath.Join(dir, format)
+		assert.Nil(t, SaveToFile(GetFeed(page, Options{GUID: GUIDPermalink}), formatDir, []string{format}))
+		guids := LoadGUIDs(formatDir)
+		assert.Equal(t, 2, len(guids), format)
+		assert.Equal(t, "https://t.me/s/telegram/1", guids["t.me/telegram/1"].ID, format)
+		assert.Equal(t, format == "rss", guids["t.me/telegram/1"].IsPermaLink, format)
+	}
+
+	// Previous GUIDs are kept when the strategy changes, new items get the new GUIDs
+	page.Posts = append(page.Posts, &parser.Post{Title: "Post 3", Link: "https://t.me/telegram/3", Channel: "telegram", MessageID: 3})
+	feed := GetFeed(page, Options{GUID: GUIDTag, PreviousGUIDs: LoadGUIDs(filepath.Join(dir, "rss"))})
+	ids := make(map[string]string)
+	for _, item := range feed.Items {
+		ids[item.Link.Href] = item.Id
+		assert.Equal(t, item.Id != "tag:t.me,2013:telegram/3", feed.PermaLinks[item])
+	}
+	assert.Equal(t, map[string]string{
+		"https://t.me/s/telegram/1": "https://t.me/s/telegram/1",
+		"https://t.me/s/telegram/2": "https://t.me/s/telegram/2",
+		"https://t.me/telegram/3":   "tag:t.me,2013:telegram/3",
+	}, ids)
+
+	// Broken files are skipped
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "rss.xml"), []byte("<rss"), 0o600))
+	assert.Equal(t, map[string]GUID{}, LoadGUIDs(dir))
+}
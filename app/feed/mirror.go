@@ -0,0 +1,236 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kulapard/tg2feed/app/parser"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MediaDir is the directory of the mirrored media in the output directory
+const MediaDir = "media"
+
+// MirrorOptions defines how post media is mirrored to the output directory
+type MirrorOptions struct {
+	// Dir is the output directory, media is saved to <Dir>/media/<channel>
+	Dir string
+	// BaseURL is the public URL of the output directory
+	BaseURL string
+	// MaxVideoSize is the max size of mirrored video in bytes, videos are not mirrored if 0
+	MaxVideoSize int64
+	// Client downloads the media, http.DefaultClient if nil
+	Client *http.Client
+}
+
+var errTooLarge = errors.New("file is too large")
+
+var channelNameRe = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+var mediaExtRe = regexp.MustCompile(`^\.[a-z0-9]{1,5}$`)
+
+// mirror saves the media of one channel
+type mirror struct {
+	opts    MirrorOptions
+	channel string
+	dir     string
+	// posts are the IDs of the posts on the page, their media is kept
+	posts map[string]bool
+}
+
+// MirrorMedia downloads post images, video thumbnails, videos and sticker previews to <dir>/media/<channel>
+// and rewrites their URLs to the public base URL, see getMediaName for the file names. Files already present
+// are not downloaded again, media of the posts no longer on the page is removed. Media failed to download
+// keeps its original URL unless it was mirrored before. Original URLs of the images and videos are kept
+// in the post SourceMedia.
+func MirrorMedia(page *parser.Page, opts MirrorOptions) error {
+	channel, err := getChannelName(page.Link)
+	if err != nil {
+		return err
+	}
+	m := &mirror{
+		opts:    opts,
+		channel: channel,
+		dir:     filepath.Join(opts.Dir, MediaDir, channel),
+		posts:   make(map[string]bool),
+	}
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return fmt.Errorf("can't create media directory: %w", err)
+	}
+
+	for _, post := range page.Posts {
+		if post.MessageID <= 0 {
+			log.Printf("[ERROR] post %s has no message ID, skipping media mirroring", post.Link)
+			continue
+		}
+		if post.SourceMedia == nil {
+			post.SourceMedia = append(append([]string{}, post.Images...), post.Videos...)
+		}
+		postID := strconv.Itoa(post.MessageID)
+		m.posts[postID] = true
+		post.Images = m.mirrorURLs(post.Images, postID, "", ".jpg", -1)
+		post.VideoThumbs = m.mirrorURLs(post.VideoThumbs, postID, "thumb", ".jpg", -1)
+		if opts.MaxVideoSize > 0 {
+			post.Videos = m.mirrorURLs(post.Videos, postID, "video", ".mp4", opts.MaxVideoSize)
+		}
+		if post.Sticker != nil {
+			post.Sticker.PreviewURL = m.mirrorURLs([]string{post.Sticker.PreviewURL}, postID, "sticker", ".webp", -1)[0]
+		}
+	}
+	return m.removeUnused()
+}
+
+// mirrorURLs mirrors the media of the kind and returns their public URLs
+func (m *mirror) mirrorURLs(mediaURLs []string, postID, kind, defaultExt string, maxSize int64) []string {
+	for i, mediaURL := range mediaURLs {
+		if mediaURL == "" {
+			continue
+		}
+		name := getMediaName(postID, kind, i, getMediaExt(mediaURL, defaultExt))
+
+		if err := m.download(mediaURL, name, maxSize); err != nil {
+			// Media mirrored before is kept, e.g. its extension changed and CDN URL is expired
+			previous := m.findMedia(getMediaName(postID, kind, i, ""))
+			if previous == "" {
+				log.Printf("[ERROR] failed to mirror %s: %v, fallback with original URL", mediaURL, err)
+				continue
+			}
+			log.Printf("[ERROR] failed to mirror %s: %v, fallback with mirrored %s", mediaURL, err, previous)
+			name = previous
+		}
+		mediaURLs[i] = strings.TrimRight(m.opts.BaseURL, "/") + "/" + MediaDir + "/" + m.channel + "/" + name
+	}
+	return mediaURLs
+}
+
+// findMedia returns the name of the mirrored file with the name without extension or empty string
+func (m *mirror) findMedia(base string) string {
+	files, err := filepath.Glob(filepath.Join(m.dir, base+".*"))
+	if err != nil || len(files) == 0 {
+		return ""
+	}
+	return filepath.Base(files[0])
+}
+
+// download saves the media to the file unless it's already present, negative maxSize means no limit
+func (m *mirror) download(mediaURL, name string, maxSize int64) error {
+	filePath := filepath.Join(m.dir, name)
+	if _, err := os.Stat(filePath); err == nil {
+		return nil
+	}
+
+	res, err := getClient(m.opts.Client).Get(mediaURL) //nolint:gosec // tolerate security risk
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code error: %s", res.Status)
+	}
+	if maxSize >= 0 && res.ContentLength > maxSize {
+		return errTooLarge
+	}
+
+	// Download to the temporary file first to not leave partial files
+	tmp, err := os.CreateTemp(m.dir, ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	body := io.Reader(res.Body)
+	if maxSize >= 0 {
+		body = io.LimitReader(res.Body, maxSize+1)
+	}
+	written, err := io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if maxSize >= 0 && written > maxSize {
+		return errTooLarge
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// removeUnused removes the media files of the posts no longer on the page
+func (m *mirror) removeUnused() error {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return fmt.Errorf("can't read media directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || m.posts[getMediaPostID(entry.Name())] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(m.dir, entry.Name())); err != nil {
+			log.Printf("[ERROR] failed to remove unused media %s: %v, skipping", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// getChannelName returns the channel name from the page link, safe to use as file name
+func getChannelName(link string) (string, error) {
+	channel := path.Base(strings.TrimRight(getURLPath(link), "/"))
+	if !channelNameRe.MatchString(channel) {
+		return "", fmt.Errorf("can't get channel name from page link: %s", link)
+	}
+	return channel, nil
+}
+
+// getClient returns the client or http.DefaultClient if it's nil
+func getClient(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
+}
+
+// getURLPath returns the path of the URL or empty string
+func getURLPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+// getMediaName returns the file name of the post media by its kind and index in the post,
+// e.g. 1.jpg and 1-1.jpg for images, 1-thumb.jpg for video thumbnail, 1-video.mp4, 1-sticker.webp
+func getMediaName(postID, kind string, index int, ext string) string {
+	name := postID
+	if kind != "" {
+		name += "-" + kind
+	}
+	if index > 0 {
+		name += "-" + strconv.Itoa(index)
+	}
+	return name + ext
+}
+
+// getMediaPostID returns the post ID of the media file name, e.g. "1" for 1-video.mp4
+func getMediaPostID(name string) string {
+	if i := strings.IndexAny(name, "-."); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// getMediaExt returns the file extension of the media URL or the default one
+func getMediaExt(mediaURL, defaultExt string) string {
+	ext := strings.ToLower(path.Ext(getURLPath(mediaURL)))
+	if !mediaExtRe.MatchString(ext) {
+		return defaultExt
+	}
+	return ext
+}
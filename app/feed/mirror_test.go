@@ -0,0 +1,130 @@
+package feed
+
+import (
+	"github.com/kulapard/tg2feed/app/parser"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMirrorMedia(t *testing.T) {
+	requests := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case "/img.jpg", "/thumb", "/sticker.webp":
+			_, _ = w.Write([]byte("image " + r.URL.Path))
+		case "/small.mp4":
+			_, _ = w.Write([]byte("video"))
+		case "/large.mp4":
+			_, _ = w.Write([]byte(strings.Repeat("v", 100)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	mediaDir := filepath.Join(dir, MediaDir, "telegram")
+	assert.Nil(t, os.MkdirAll(mediaDir, 0o750))
+	assert.Nil(t, os.WriteFile(filepath.Join(mediaDir, "1.jpg"), []byte("old post"), 0o600))
+	assert.Nil(t, os.WriteFile(filepath.Join(mediaDir, ".download-1"), []byte("partial"), 0o600))
+
+	// CDN URLs are different on every page load
+	getPage := func(token string) *parser.Page {
+		return &parser.Page{
+			Link: "https://t.me/telegram",
+			Posts: []*parser.Post{
+				{Link: "https://t.me/s/telegram/2", MessageID: 2, Images: []string{ts.URL + "/img.jpg?" + token, ts.URL + "/missing.png"}},
+				{Link: "https://t.me/s/telegram/3", MessageID: 3, VideoThumbs: []string{ts.URL + "/thumb?" + token},
+					Videos: []string{ts.URL + "/small.mp4?" + token, ts.URL + "/large.mp4"}},
+				{Link: "https://t.me/s/telegram/4", MessageID: 4, Sticker: &parser.Sticker{URL: ts.URL + "/sticker.tgs", PreviewURL: ts.URL + "/sticker.webp"}},
+				{Link: "https://t.me/s/telegram", Images: []string{ts.URL + "/img.jpg"}},
+			},
+		}
+	}
+	opts := MirrorOptions{Dir: dir, BaseURL: "https://example.com/feeds/", MaxVideoSize: 50}
+
+	page := getPage("1")
+	assert.Nil(t, MirrorMedia(page, opts))
+
+	const baseURL = "https://example.com/feeds/media/telegram/"
+	assert.Equal(t, []string{baseURL + "2.jpg", ts.URL + "/missing.png"}, page.Posts[0].Images)
+	assert.Equal(t, []string{ts.URL + "/img.jpg?1", ts.URL + "/missing.png"}, page.Posts[0].SourceMedia)
+	assert.Equal(t, []string{baseURL + "3-thumb.jpg"}, page.Posts[1].VideoThumbs)
+	assert.Equal(t, []string{baseURL + "3-video.mp4", ts.URL + "/large.mp4"}, page.Posts[1].Videos)
+	assert.Equal(t, []string{ts.URL + "/small.mp4?1", ts.URL + "/large.mp4"}, page.Posts[1].SourceMedia)
+	assert.Equal(t, baseURL+"4-sticker.webp", page.Posts[2].Sticker.PreviewURL)
+	assert.Equal(t, ts.URL+"/sticker.tgs", page.Posts[2].Sticker.URL)
+	assert.Equal(t, []string{ts.URL + "/img.jpg"}, page.Posts[3].Images)
+
+	data, err := os.ReadFile(filepath.Join(mediaDir, "2.jpg"))
+	assert.Nil(t, err)
+	assert.Equal(t, "image /img.jpg", string(data))
+
+	// Media of the old post and partial downloads are removed
+	assert.Equal(t, []string{"2.jpg", "3-thumb.jpg", "3-video.mp4", "4-sticker.webp"}, readDirNames(t, mediaDir))
+
+	// Files already present are not downloaded again even if CDN URLs changed
+	page = getPage("2")
+	assert.Nil(t, MirrorMedia(page, opts))
+	assert.Equal(t, []string{baseURL + "2.jpg", ts.URL + "/missing.png"}, page.Posts[0].Images)
+	assert.Equal(t, 1, requests["/img.jpg"])
+	assert.Equal(t, 1, requests["/thumb"])
+	assert.Equal(t, 1, requests["/small.mp4"])
+
+	// Mirrored media is kept if it fails to download again, e.g. its CDN URL is expired
+	page = getPage("3")
+	page.Posts[0].Images = []string{ts.URL + "/expired.png"}
+	assert.Nil(t, MirrorMedia(page, opts))
+	assert.Equal(t, []string{baseURL + "2.jpg"}, page.Posts[0].Images)
+
+	// Videos are not mirrored without size limit, mirrored ones are kept while the post is on the page
+	page = getPage("4")
+	opts.MaxVideoSize = 0
+	assert.Nil(t, MirrorMedia(page, opts))
+	assert.Equal(t, []string{ts.URL + "/small.mp4?4", ts.URL + "/large.mp4"}, page.Posts[1].Videos)
+	assert.Equal(t, []string{"2.jpg", "3-thumb.jpg", "3-video.mp4", "4-sticker.webp"}, readDirNames(t, mediaDir))
+
+	// Media of the posts no longer on the page is removed
+	page = getPage("5")
+	page.Posts = page.Posts[:1]
+	assert.Nil(t, MirrorMedia(page, opts))
+	assert.Equal(t, []string{"2.jpg"}, readDirNames(t, mediaDir))
+}
+
+func readDirNames(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestMirrorMedia_WrongChannel(t *testing.T) {
+	err := MirrorMedia(&parser.Page{Link: "https://t.me/"}, MirrorOptions{Dir: t.TempDir()})
+	assert.NotNil(t, err)
+}
+
+func TestGetMediaName(t *testing.T) {
+	assert.Equal(t, "2.jpg", getMediaName("2", "", 0, ".jpg"))
+	assert.Equal(t, "2-1.png", getMediaName("2", "", 1, ".png"))
+	assert.Equal(t, "2-thumb.jpg", getMediaName("2", "thumb", 0, ".jpg"))
+	assert.Equal(t, "2-video-2", getMediaName("2", "video", 2, ""))
+
+	assert.Equal(t, "2", getMediaPostID("2.jpg"))
+	assert.Equal(t, "2", getMediaPostID("2-video-2.mp4"))
+	assert.Equal(t, "", getMediaPostID(".download-1"))
+}
+
+func TestGetMediaExt(t *testing.T) {
+	assert.Equal(t, ".png", getMediaExt("https://cdn.example.com/file/a.PNG?x=1", ".jpg"))
+	assert.Equal(t, ".jpg", getMediaExt("https://cdn.example.com/file/abc", ".jpg"))
+	assert.Equal(t, ".jpg", getMediaExt("https://cdn.example.com/file/a.very-long", ".jpg"))
+}
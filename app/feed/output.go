@@ -0,0 +1,201 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/gorilla/feeds"
+	"github.com/kulapard/tg2feed/app/parser"
+)
+
+// tgNamespace is the XML namespace of the channel extension elements
+const tgNamespace = "https://github.com/kulapard/tg2feed"
+
+// tgExtension is the channel counters and verified badge: tg: prefixed elements in XML and _tg object in JSON Feed
+type tgExtension struct {
+	Subscribers int  `xml:"tg:subscribers,omitempty" json:"subscribers,omitempty"`
+	Photos      int  `xml:"tg:photos,omitempty" json:"photos,omitempty"`
+	Videos      int  `xml:"tg:videos,omitempty" json:"videos,omitempty"`
+	Links       int  `xml:"tg:links,omitempty" json:"links,omitempty"`
+	Files       int  `xml:"tg:files,omitempty" json:"files,omitempty"`
+	Verified    bool `xml:"tg:verified,omitempty" json:"verified,omitempty"`
+}
+
+// xmlFeed adapts any XML-ready value to feeds.XmlFeed
+type xmlFeed struct {
+	value any
+}
+
+// FeedXml returns the XML-ready value
+func (x *xmlFeed) FeedXml() any { //nolint:revive,stylecheck // implements feeds.XmlFeed
+	return x.value
+}
+
+// rssSource is the RSS 2.0 <source url="...">title</source> element
+type rssSource struct {
+	XMLName xml.Name `xml:"source"`
+	URL     string   `xml:"url,attr"`
+	Title   string   `xml:",chardata"`
+}
+
+// rssGUID is the RSS 2.0 <guid isPermaLink="...">id</guid> element
+type rssGUID struct {
+	XMLName     xml.Name `xml:"guid"`
+	IsPermaLink bool     `xml:"isPermaLink,attr"`
+	ID          string   `xml:",chardata"`
+}
+
+// rssItem extends gorilla RSS item with the elements it doesn't support
+type rssItem struct {
+	*feeds.RssItem
+	GUID   *rssGUID
+	Source *rssSource
+}
+
+// rssFeed extends gorilla RSS channel with the extended items
+type rssFeed struct {
+	*feeds.RssFeed
+	*tgExtension
+	Items []*rssItem `xml:"item"`
+}
+
+type rssFeedXML struct {
+	XMLName          xml.Name `xml:"rss"`
+	Version          string   `xml:"version,attr"`
+	ContentNamespace string   `xml:"xmlns:content,attr"`
+	TgNamespace      string   `xml:"xmlns:tg,attr,omitempty"`
+	Channel          *rssFeed
+}
+
+// atomSource is the Atom <source> element with the original feed metadata
+type atomSource struct {
+	XMLName xml.Name `xml:"source"`
+	ID      string   `xml:"id,omitempty"`
+	Title   string   `xml:"title,omitempty"`
+	Link    *feeds.AtomLink
+	Icon    string `xml:"icon,omitempty"`
+}
+
+// atomEntry extends gorilla Atom entry with the elements it doesn't support
+type atomEntry struct {
+	*feeds.AtomEntry
+	Source *atomSource
+}
+
+// atomFeed extends gorilla Atom feed with the extended entries
+type atomFeed struct {
+	*feeds.AtomFeed
+	*tgExtension
+	Lang        string       `xml:"xml:lang,attr,omitempty"`
+	TgNamespace string       `xml:"xmlns:tg,attr,omitempty"`
+	Entries     []*atomEntry `xml:"entry"`
+}
+
+// jsonFeed extends gorilla JSON Feed with the channel extension
+type jsonFeed struct {
+	*feeds.JSONFeed
+	Tg *tgExtension `json:"_tg,omitempty"`
+}
+
+// getTgExtension returns the channel extension of the feed or nil if there is no data
+func getTgExtension(f *Feed) *tgExtension {
+	ext := tgExtension{
+		Subscribers: f.Counters.Subscribers,
+		Photos:      f.Counters.Photos,
+		Videos:      f.Counters.Videos,
+		Links:       f.Counters.Links,
+		Files:       f.Counters.Files,
+		Verified:    f.Verified,
+	}
+	if ext == (tgExtension{}) {
+		return nil
+	}
+	return &ext
+}
+
+// toRSS returns RSS 2.0 representation of the feed
+func toRSS(f *Feed) (string, error) {
+	channel := &rssFeed{RssFeed: (&feeds.Rss{Feed: f.Feed}).RssFeed(), tgExtension: getTgExtension(f)}
+	channel.Language = f.Language
+	for i, item := range channel.RssFeed.Items {
+		rItem := &rssItem{RssItem: item}
+		if item.Guid != "" {
+			rItem.GUID = &rssGUID{ID: item.Guid, IsPermaLink: f.PermaLinks[f.Items[i]]}
+		}
+		// RSS item has only one source
+		if srcs := f.Sources[f.Items[i]]; len(srcs) > 0 {
+			rItem.Source = &rssSource{URL: srcs[0].Link, Title: srcs[0].Title}
+		}
+		channel.Items = append(channel.Items, rItem)
+	}
+	rss := &rssFeedXML{
+		Version:          "2.0",
+		ContentNamespace: "http://purl.org/rss/1.0/modules/content/",
+		Channel:          channel,
+	}
+	if channel.tgExtension != nil {
+		rss.TgNamespace = tgNamespace
+	}
+	return feeds.ToXML(&xmlFeed{rss})
+}
+
+// toAtom returns Atom representation of the feed
+func toAtom(f *Feed) (string, error) {
+	feed := &atomFeed{AtomFeed: (&feeds.Atom{Feed: f.Feed}).AtomFeed(), tgExtension: getTgExtension(f), Lang: f.Language}
+	if feed.tgExtension != nil {
+		feed.TgNamespace = tgNamespace
+	}
+	if f.Image != nil {
+		feed.Icon = f.Image.Url
+		feed.Logo = f.Image.Url
+	}
+	for i, entry := range feed.AtomFeed.Entries {
+		aEntry := &atomEntry{AtomEntry: entry}
+		// Atom entry has only one source
+		if srcs := f.Sources[f.Items[i]]; len(srcs) > 0 {
+			src := srcs[0]
+			aEntry.Source = &atomSource{
+				ID:    src.Link,
+				Title: src.Title,
+				Link:  &feeds.AtomLink{Href: src.Link, Rel: "alternate"},
+				Icon:  src.ImageURL,
+			}
+		}
+		feed.Entries = append(feed.Entries, aEntry)
+	}
+	return feeds.ToXML(&xmlFeed{feed})
+}
+
+// toJSON returns JSON Feed representation of the feed
+func toJSON(f *Feed) (string, error) {
+	feed := (&feeds.JSON{Feed: f.Feed}).JSONFeed()
+	feed.Language = f.Language
+	if f.Image != nil {
+		feed.Icon = f.Image.Url
+	}
+	for i, item := range feed.Items {
+		// Items content is HTML description, plain text is provided for non-HTML consumers
+		if item.ContentHTML == "" {
+			item.ContentHTML = f.Items[i].Description
+		}
+		item.ContentText = parser.HTMLToText(item.ContentHTML)
+		// Video post has the video enclosure, its thumbnail is the item image
+		if post := f.Posts[f.Items[i]]; post != nil && item.Image == "" && len(post.VideoThumbs) > 0 {
+			item.Image = post.VideoThumbs[0]
+		}
+
+		srcs := f.Sources[f.Items[i]]
+		if len(srcs) == 0 {
+			continue
+		}
+		item.Authors = make([]*feeds.JSONAuthor, len(srcs))
+		for j, src := range srcs {
+			item.Authors[j] = &feeds.JSONAuthor{Name: src.Title, Url: src.Link, Avatar: src.ImageURL}
+		}
+		item.Author = item.Authors[0]
+	}
+	data, err := json.MarshalIndent(&jsonFeed{JSONFeed: feed, Tg: getTgExtension(f)}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,153 @@
+package feed
+
+import (
+	"github.com/gorilla/feeds"
+	"github.com/kulapard/tg2feed/app/parser"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func getFeedWithSource() *Feed {
+	item1 := &feeds.Item{
+		Title:   "Post 1",
+		Link:    &feeds.Link{Href: "https://t.me/s/telegram/1"},
+		Id:      "1",
+		Created: time.Date(2023, 12, 15, 16, 29, 45, 0, time.UTC),
+	}
+	item2 := &feeds.Item{
+		Title:   "Post 2",
+		Link:    &feeds.Link{Href: "https://t.me/s/durov/2"},
+		Id:      "2",
+		Created: time.Date(2023, 12, 14, 16, 29, 45, 0, time.UTC),
+	}
+	return &Feed{
+		Feed: &feeds.Feed{
+			Title: "Telegram Feed",
+			Link:  &feeds.Link{Href: "https://github.com/kulapard/tg2feed"},
+			Items: []*feeds.Item{item1, item2},
+		},
+		Sources: map[*feeds.Item][]*Source{
+			item1: {{Title: "Telegram", Link: "https://t.me/s/telegram", ImageURL: "https://telegram.org/img/t_logo.png"}},
+		},
+	}
+}
+
+func TestToRSS(t *testing.T) {
+	content, err := toRSS(getFeedWithSource())
+	assert.Nil(t, err)
+	assert.Contains(t, content, `<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">`)
+	assert.Contains(t, content, `<source url="https://t.me/s/telegram">Telegram</source>`)
+	assert.Equal(t, 1, strings.Count(content, "<source"))
+	assert.Equal(t, 2, strings.Count(content, "<item>"))
+	assert.Contains(t, content, `<guid isPermaLink="false">1</guid>`)
+	assert.Equal(t, 2, strings.Count(content, "<guid"))
+}
+
+func TestToRSS_PermaLink(t *testing.T) {
+	f := getFeedWithSource()
+	f.Items[0].Id = f.Items[0].Link.Href
+	f.PermaLinks = map[*feeds.Item]bool{f.Items[0]: true}
+	content, err := toRSS(f)
+	assert.Nil(t, err)
+	assert.Contains(t, content, `<guid isPermaLink="true">https://t.me/s/telegram/1</guid>`)
+	assert.Contains(t, content, `<guid isPermaLink="false">2</guid>`)
+}
+
+func TestToAtom(t *testing.T) {
+	content, err := toAtom(getFeedWithSource())
+	assert.Nil(t, err)
+	assert.Contains(t, content, `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	assert.Contains(t, content, `<source>
+      <id>https://t.me/s/telegram</id>
+      <title>Telegram</title>
+      <link href="https://t.me/s/telegram" rel="alternate"></link>
+      <icon>https://telegram.org/img/t_logo.png</icon>
+    </source>`)
+	assert.Equal(t, 1, strings.Count(content, "<source>"))
+	assert.Equal(t, 2, strings.Count(content, "<entry>"))
+}
+
+func TestToJSON(t *testing.T) {
+	content, err := toJSON(getFeedWithSource())
+	assert.Nil(t, err)
+	assert.Contains(t, content, `"authors": [
+        {
+          "name": "Telegram",
+          "url": "https://t.me/s/telegram",
+          "avatar": "https://telegram.org/img/t_logo.png"
+        }
+      ]`)
+	assert.Equal(t, 1, strings.Count(content, `"authors"`))
+}
+
+func TestOutput_LanguageAndImage(t *testing.T) {
+	f := getFeedWithSource()
+	f.Language = "en"
+	f.Image = &feeds.Image{Url: "https://example.com/logo.png"}
+
+	content, err := toRSS(f)
+	assert.Nil(t, err)
+	assert.Contains(t, content, "<language>en</language>")
+	assert.Contains(t, content, "<url>https://example.com/logo.png</url>")
+
+	content, err = toAtom(f)
+	assert.Nil(t, err)
+	assert.Contains(t, content, `<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="en">`)
+	assert.Contains(t, content, "<icon>https://example.com/logo.png</icon>")
+	assert.Contains(t, content, "<logo>https://example.com/logo.png</logo>")
+
+	content, err = toJSON(f)
+	assert.Nil(t, err)
+	assert.Contains(t, content, `"language": "en"`)
+	assert.Contains(t, content, `"icon": "https://example.com/logo.png"`)
+}
+
+func TestToJSON_Content(t *testing.T) {
+	f := getFeedWithSource()
+	f.Items[0].Description = `<p>Hello <b>world</b></p>` + "\n" + `<p><a href="https://t.me/s/telegram">Telegram</a></p>`
+
+	content, err := toJSON(f)
+	assert.Nil(t, err)
+	assert.Contains(t, content, `"content_html": "\u003cp\u003eHello \u003cb\u003eworld\u003c/b\u003e\u003c/p\u003e\n`)
+	assert.Contains(t, content, `"content_text": "Hello world\n\nTelegram (https://t.me/s/telegram)"`)
+}
+
+func TestToJSON_VideoThumb(t *testing.T) {
+	page := &parser.Page{Title: "Telegram", Link: "https://t.me/s/telegram", Posts: []*parser.Post{
+		{Link: "https://t.me/s/telegram/1", Videos: []string{"https://cdn.telegram.org/file/video.mp4"},
+			VideoThumbs: []string{"https://cdn.telegram.org/file/thumb.jpg"}},
+	}}
+	content, err := toJSON(GetFeed(page, Options{}))
+	assert.Nil(t, err)
+	assert.Contains(t, content, `"image": "https://cdn.telegram.org/file/thumb.jpg"`)
+}
+
+func TestOutput_Channel(t *testing.T) {
+	f := getFeedWithSource()
+	content, err := toRSS(f)
+	assert.Nil(t, err)
+	assert.NotContains(t, content, "tg:")
+
+	f.Counters = parser.Counters{Subscribers: 12300, Photos: 5}
+	f.Verified = true
+
+	content, err = toRSS(f)
+	assert.Nil(t, err)
+	assert.Contains(t, content, `xmlns:tg="https://github.com/kulapard/tg2feed"`)
+	assert.Contains(t, content, "<tg:subscribers>12300</tg:subscribers>\n    <tg:photos>5</tg:photos>\n    <tg:verified>true</tg:verified>")
+
+	content, err = toAtom(f)
+	assert.Nil(t, err)
+	assert.Contains(t, content, `xmlns:tg="https://github.com/kulapard/tg2feed"`)
+	assert.Contains(t, content, "<tg:subscribers>12300</tg:subscribers>")
+
+	content, err = toJSON(f)
+	assert.Nil(t, err)
+	assert.Contains(t, content, `"_tg": {
+    "subscribers": 12300,
+    "photos": 5,
+    "verified": true
+  }`)
+}
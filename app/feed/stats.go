@@ -0,0 +1,281 @@
+package feed
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/kulapard/tg2feed/app/parser"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatsDir is the directory of the channel statistics in the output directory
+const StatsDir = "stats"
+
+// StatsFormat is the file format of the statistics history
+type StatsFormat string
+
+// Supported statistics formats
+const (
+	StatsCSV   StatsFormat = "csv"
+	StatsJSONL StatsFormat = "jsonl"
+)
+
+// StatsOptions defines how channel statistics are tracked
+type StatsOptions struct {
+	// Dir is the output directory, statistics are saved to <Dir>/stats/<channel>.<format>
+	Dir    string
+	Format StatsFormat
+	// Sparkline saves SVG chart of the subscribers history to <Dir>/stats/<channel>.svg
+	Sparkline bool
+}
+
+// StatsRecord is the channel statistics of one run
+type StatsRecord struct {
+	Time        time.Time `json:"time"`
+	Subscribers int       `json:"subscribers"`
+	// Posts is the number of posts on the page
+	Posts       int     `json:"posts"`
+	PostsPerDay float64 `json:"posts_per_day"`
+	MedianViews int     `json:"median_views"`
+}
+
+var statsCSVHeader = []string{"time", "subscribers", "posts", "posts_per_day", "median_views"}
+
+// Max number of the latest records shown on the sparkline
+const sparklinePoints = 60
+
+// ParseStatsFormat parses the statistics format name, empty string means statistics are disabled
+func ParseStatsFormat(str string) (StatsFormat, error) {
+	format := StatsFormat(strings.TrimSpace(str))
+	switch format {
+	case "", StatsCSV, StatsJSONL:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown stats format: %s", str)
+}
+
+// GetStatsRecord returns the statistics of the page at the time
+func GetStatsRecord(page *parser.Page, now time.Time) StatsRecord {
+	record := StatsRecord{
+		Time:        now.UTC().Truncate(time.Second),
+		Subscribers: page.Counters.Subscribers,
+		Posts:       len(page.Posts),
+	}
+	if len(page.Posts) == 0 {
+		return record
+	}
+
+	views := make([]int, 0, len(page.Posts))
+	oldest, newest := page.Posts[0].Created, page.Posts[0].Created
+	for _, post := range page.Posts {
+		if post.Views > 0 {
+			views = append(views, post.Views)
+		}
+		if post.Created.Before(oldest) {
+			oldest = post.Created
+		}
+		if post.Created.After(newest) {
+			newest = post.Created
+		}
+	}
+	record.MedianViews = median(views)
+
+	// Posts of the page span at least one day
+	days := newest.Sub(oldest).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+	record.PostsPerDay = float64(len(page.Posts)) / days
+	return record
+}
+
+// median returns the median of the values, 0 if there are none
+func median(values []int) int {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// SaveStats appends the page statistics to <dir>/stats/<channel>.<format>
+// and saves the subscribers sparkline if requested
+func SaveStats(page *parser.Page, opts StatsOptions) error {
+	channel, err := getChannelName(page.Link)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(opts.Dir, StatsDir)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("can't create stats directory: %w", err)
+	}
+
+	fileName := filepath.Join(dir, channel+"."+string(opts.Format))
+	if err := appendStats(fileName, opts.Format, GetStatsRecord(page, time.Now())); err != nil {
+		return err
+	}
+	log.Printf("[INFO] stats saved to %s", fileName)
+
+	if !opts.Sparkline {
+		return nil
+	}
+	records, err := loadStats(fileName, opts.Format)
+	if err != nil {
+		return err
+	}
+	if len(records) > sparklinePoints {
+		records = records[len(records)-sparklinePoints:]
+	}
+	subscribers := make([]int, len(records))
+	for i, record := range records {
+		subscribers[i] = record.Subscribers
+	}
+	return save(filepath.Join(dir, channel+".svg"), Sparkline(subscribers))
+}
+
+// appendStats appends the record to the file, CSV header is written to the new file
+func appendStats(fileName string, format StatsFormat, record StatsRecord) error {
+	_, statErr := os.Stat(fileName)
+	isNew := os.IsNotExist(statErr)
+
+	fh, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644) //nolint:gosec // tolerable security risk
+	if err != nil {
+		return err
+	}
+	defer fh.Close() // nolint
+
+	if format == StatsJSONL {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		_, err = fh.Write(append(data, '\n'))
+		return err
+	}
+
+	w := csv.NewWriter(fh)
+	if isNew {
+		if err := w.Write(statsCSVHeader); err != nil {
+			return err
+		}
+	}
+	if err := w.Write([]string{
+		record.Time.Format(time.RFC3339),
+		strconv.Itoa(record.Subscribers),
+		strconv.Itoa(record.Posts),
+		strconv.FormatFloat(record.PostsPerDay, 'f', 2, 64),
+		strconv.Itoa(record.MedianViews),
+	}); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// loadStats reads the statistics history, broken records are skipped
+func loadStats(fileName string, format StatsFormat) ([]StatsRecord, error) {
+	fh, err := os.Open(fileName) //nolint:gosec // tolerable security risk
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() // nolint
+
+	var records []StatsRecord
+	if format == StatsJSONL {
+		scanner := bufio.NewScanner(fh)
+		for scanner.Scan() {
+			var record StatsRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				log.Printf("[ERROR] failed to parse stats record in %s: %v, skipping", fileName, err)
+				continue
+			}
+			records = append(records, record)
+		}
+		return records, scanner.Err()
+	}
+
+	rows, err := csv.NewReader(fh).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if len(row) != len(statsCSVHeader) || row[0] == statsCSVHeader[0] {
+			continue
+		}
+		record, err := parseStatsRow(row)
+		if err != nil {
+			log.Printf("[ERROR] failed to parse stats record in %s: %v, skipping", fileName, err)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseStatsRow parses CSV row of the statistics record
+func parseStatsRow(row []string) (StatsRecord, error) {
+	var record StatsRecord
+	var err error
+	if record.Time, err = time.Parse(time.RFC3339, row[0]); err != nil {
+		return record, err
+	}
+	if record.Subscribers, err = strconv.Atoi(row[1]); err != nil {
+		return record, err
+	}
+	if record.Posts, err = strconv.Atoi(row[2]); err != nil {
+		return record, err
+	}
+	if record.PostsPerDay, err = strconv.ParseFloat(row[3], 64); err != nil {
+		return record, err
+	}
+	record.MedianViews, err = strconv.Atoi(row[4])
+	return record, err
+}
+
+// Sparkline size in pixels
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 30
+)
+
+// Sparkline returns SVG line chart of the values scaled to fit its height
+func Sparkline(values []int) string {
+	var points []string
+	if len(values) > 0 {
+		low, high := values[0], values[0]
+		for _, v := range values {
+			low, high = min(low, v), max(high, v)
+		}
+		step := 0.0
+		if len(values) > 1 {
+			step = float64(sparklineWidth) / float64(len(values)-1)
+		}
+		for i, v := range values {
+			// Flat line goes through the middle
+			y := float64(sparklineHeight) / 2
+			if high > low {
+				y = float64(sparklineHeight) - float64(v-low)/float64(high-low)*float64(sparklineHeight)
+			}
+			points = append(points, strconv.FormatFloat(float64(i)*step, 'f', 1, 64)+","+strconv.FormatFloat(y, 'f', 1, 64))
+		}
+		if len(values) == 1 {
+			// Single value is drawn as a flat line
+			points = append(points, strconv.FormatFloat(sparklineWidth, 'f', 1, 64)+","+strings.Split(points[0], ",")[1])
+		}
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+
+		`<polyline fill="none" stroke="currentColor" stroke-width="1.5" points="%s"/></svg>`+"\n",
+		sparklineWidth, sparklineHeight, sparklineWidth, sparklineHeight, strings.Join(points, " "))
+}
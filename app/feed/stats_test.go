@@ -0,0 +1,148 @@
+package feed
+
+import (
+	"github.com/kulapard/tg2feed/app/parser"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func getStatsPage() *parser.Page {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	return &parser.Page{
+		Link:     "https://t.me/telegram",
+		Counters: parser.Counters{Subscribers: 1000},
+		Posts: []*parser.Post{
+			{Created: created, Views: 300},
+			{Created: created.Add(24 * time.Hour), Views: 100},
+			{Created: created.Add(48 * time.Hour)},
+			{Created: created.Add(96 * time.Hour), Views: 200},
+		},
+	}
+}
+
+func TestParseStatsFormat(t *testing.T) {
+	tbl := []struct {
+		inp string
+		out StatsFormat
+		err bool
+	}{
+		{"", "", false},
+		{"csv", StatsCSV, false},
+		{" jsonl ", StatsJSONL, false},
+		{"xml", "", true},
+	}
+	for _, tb := range tbl {
+		format, err := ParseStatsFormat(tb.inp)
+		assert.Equal(t, tb.err, err != nil)
+		assert.Equal(t, tb.out, format)
+	}
+}
+
+func TestGetStatsRecord(t *testing.T) {
+	now := time.Date(2024, 1, 10, 8, 30, 15, 500, time.UTC)
+	record := GetStatsRecord(getStatsPage(), now)
+	assert.Equal(t, StatsRecord{
+		Time:        time.Date(2024, 1, 10, 8, 30, 15, 0, time.UTC),
+		Subscribers: 1000,
+		Posts:       4,
+		PostsPerDay: 1,
+		MedianViews: 200,
+	}, record)
+
+	// Posts of the same day
+	page := getStatsPage()
+	page.Posts = page.Posts[:1]
+	record = GetStatsRecord(page, now)
+	assert.Equal(t, 1.0, record.PostsPerDay)
+	assert.Equal(t, 300, record.MedianViews)
+
+	record = GetStatsRecord(&parser.Page{}, now)
+	assert.Equal(t, StatsRecord{Time: record.Time}, record)
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, 0, median(nil))
+	assert.Equal(t, 2, median([]int{3, 1, 2}))
+	assert.Equal(t, 25, median([]int{40, 10, 30, 20}))
+}
+
+func TestSaveStats(t *testing.T) {
+	for _, format := range []StatsFormat{StatsCSV, StatsJSONL} {
+		dir := t.TempDir()
+		opts := StatsOptions{Dir: dir, Format: format, Sparkline: true}
+
+		page := getStatsPage()
+		assert.Nil(t, SaveStats(page, opts))
+		page.Counters.Subscribers = 1500
+		assert.Nil(t, SaveStats(page, opts))
+
+		fileName := filepath.Join(dir, StatsDir, "telegram."+string(format))
+		records, err := loadStats(fileName, format)
+		assert.Nil(t, err)
+		assert.Len(t, records, 2)
+		assert.Equal(t, 1000, records[0].Subscribers)
+		assert.Equal(t, 1500, records[1].Subscribers)
+		assert.Equal(t, 200, records[1].MedianViews)
+		assert.Equal(t, 1.0, records[1].PostsPerDay)
+
+		svg, err := os.ReadFile(filepath.Join(dir, StatsDir, "telegram.svg"))
+		assert.Nil(t, err)
+		assert.Contains(t, string(svg), `points="0.0,30.0 120.0,0.0"`)
+	}
+
+	// CSV header is written once
+	dir := t.TempDir()
+	assert.Nil(t, SaveStats(getStatsPage(), StatsOptions{Dir: dir, Format: StatsCSV}))
+	assert.Nil(t, SaveStats(getStatsPage(), StatsOptions{Dir: dir, Format: StatsCSV}))
+	data, err := os.ReadFile(filepath.Join(dir, StatsDir, "telegram.csv"))
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "time,subscribers,posts,posts_per_day,median_views", lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], ",1000,4,1.00,200"))
+	_, err = os.Stat(filepath.Join(dir, StatsDir, "telegram.svg"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NotNil(t, SaveStats(&parser.Page{Link: "https://t.me/"}, StatsOptions{Dir: dir, Format: StatsCSV}))
+}
+
+func TestLoadStats_Broken(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "telegram.jsonl")
+	assert.Nil(t, os.WriteFile(fileName, []byte("{\"subscribers\":5}\nbroken\n"), 0o600))
+	records, err := loadStats(fileName, StatsJSONL)
+	assert.Nil(t, err)
+	assert.Equal(t, []StatsRecord{{Subscribers: 5}}, records)
+
+	fileName = filepath.Join(dir, "telegram.csv")
+	assert.Nil(t, os.WriteFile(fileName, []byte("time,subscribers,posts,posts_per_day,median_views\n"+
+		"2024-01-01T00:00:00Z,7,1,0.50,3\nwrong,1,1,1,1\n"), 0o600))
+	records, err = loadStats(fileName, StatsCSV)
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, 7, records[0].Subscribers)
+
+	_, err = loadStats(filepath.Join(dir, "missing.csv"), StatsCSV)
+	assert.NotNil(t, err)
+}
+
+func TestSparkline(t *testing.T) {
+	tbl := []struct {
+		inp    []int
+		points string
+	}{
+		{nil, `points=""`},
+		{[]int{5}, `points="0.0,15.0 120.0,15.0"`},
+		{[]int{5, 5, 5}, `points="0.0,15.0 60.0,15.0 120.0,15.0"`},
+		{[]int{10, 20, 15}, `points="0.0,30.0 60.0,0.0 120.0,15.0"`},
+	}
+	for _, tb := range tbl {
+		svg := Sparkline(tb.inp)
+		assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="120" height="30"`))
+		assert.Contains(t, svg, tb.points)
+	}
+}
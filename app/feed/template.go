@@ -0,0 +1,68 @@
+package feed
+
+import (
+	"github.com/kulapard/tg2feed/app/parser"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+)
+
+// ItemData is the data available in the item title and body templates
+type ItemData struct {
+	Post *parser.Post
+	Page *parser.Page
+	// Text is the sanitized post HTML, rendered as is by the body template
+	Text htmltemplate.HTML
+}
+
+var templateFuncs = map[string]any{
+	"shorten": parser.ShortenText,
+}
+
+// ParseTitleTemplate parses the item title template. Empty text returns nil template.
+func ParseTitleTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New("title").Funcs(templateFuncs).Parse(text)
+}
+
+// ParseBodyTemplate parses the item body template. Empty text returns nil template.
+func ParseBodyTemplate(text string) (*htmltemplate.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return htmltemplate.New("body").Funcs(templateFuncs).Parse(text)
+}
+
+func newItemData(page *parser.Page, post *parser.Post) *ItemData {
+	return &ItemData{
+		Post: post,
+		Page: page,
+		Text: htmltemplate.HTML(post.Text), //nolint:gosec // post text is sanitized by parser
+	}
+}
+
+// renderTitle returns the item title rendered by the template or the post title if template is not set
+func renderTitle(tmpl *template.Template, data *ItemData) (string, error) {
+	if tmpl == nil {
+		return data.Post.Title, nil
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return data.Post.Title, err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// renderBody returns the item body rendered by the template or the post text if template is not set
+func renderBody(tmpl *htmltemplate.Template, data *ItemData) (string, error) {
+	if tmpl == nil {
+		return data.Post.Text, nil
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return data.Post.Text, err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -0,0 +1,67 @@
+package feed
+
+import (
+	"github.com/kulapard/tg2feed/app/parser"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseTemplates(t *testing.T) {
+	titleTmpl, err := ParseTitleTemplate("")
+	assert.Nil(t, err)
+	assert.Nil(t, titleTmpl)
+
+	bodyTmpl, err := ParseBodyTemplate("")
+	assert.Nil(t, err)
+	assert.Nil(t, bodyTmpl)
+
+	_, err = ParseTitleTemplate("{{.Post.Title")
+	assert.NotNil(t, err)
+
+	_, err = ParseBodyTemplate("{{.Post.Text")
+	assert.NotNil(t, err)
+}
+
+func TestGetFeed_Templates(t *testing.T) {
+	page := &parser.Page{
+		Title: "Channel",
+		Link:  "https://t.me/s/telegram",
+		Posts: []*parser.Post{
+			{
+				Title:   "First line",
+				Link:    "https://t.me/s/telegram/1",
+				Text:    "<p>First line</p><p>Second line</p>",
+				Created: time.Date(2023, 12, 15, 16, 29, 45, 0, time.UTC),
+				Images:  []string{"https://telegram.org/img/1.png"},
+			},
+		},
+	}
+
+	titleTmpl, err := ParseTitleTemplate(`[{{.Page.Title}}] {{shorten .Post.Title 5}}`)
+	assert.Nil(t, err)
+	bodyTmpl, err := ParseBodyTemplate(`{{range .Post.Images}}<img src="{{.}}">{{end}}{{.Text}}<p>{{.Post.Created.Format "2006-01-02"}}</p>`)
+	assert.Nil(t, err)
+
+	feed := GetFeed(page, Options{TitleTemplate: titleTmpl, BodyTemplate: bodyTmpl})
+	assert.Equal(t, 1, len(feed.Items))
+	assert.Equal(t, "[Channel] First...", feed.Items[0].Title)
+	assert.Equal(t, `<img src="https://telegram.org/img/1.png"><p>First line</p><p>Second line</p><p>2023-12-15</p>`, feed.Items[0].Description)
+}
+
+func TestGetFeed_TemplateFallback(t *testing.T) {
+	page := &parser.Page{
+		Title: "Channel",
+		Posts: []*parser.Post{{Title: "Post title", Text: "<p>Post text</p>"}},
+	}
+
+	// Both templates fail on execution: strings have no fields
+	titleTmpl, err := ParseTitleTemplate(`{{.Post.Title.Foo}}`)
+	assert.Nil(t, err)
+	bodyTmpl, err := ParseBodyTemplate(`{{.Post.Text.Foo}}`)
+	assert.Nil(t, err)
+
+	feed := GetFeed(page, Options{TitleTemplate: titleTmpl, BodyTemplate: bodyTmpl})
+	assert.Equal(t, "Post title", feed.Items[0].Title)
+	assert.Equal(t, "<p>Post text</p>", feed.Items[0].Description)
+}
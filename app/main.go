@@ -1,12 +1,18 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/gorilla/feeds"
+	"github.com/PuerkitoBio/goquery"
 	"github.com/kulapard/tg2feed/app/feed"
 	"github.com/kulapard/tg2feed/app/parser"
+	"github.com/kulapard/tg2feed/app/replay"
+	"io"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -21,10 +27,95 @@ type Config struct {
 	OutputDir        string
 	TelegramChannels []string
 	Formats          []string
+	// Optional Go templates for item title and body
+	ItemTitleTemplate string
+	ItemBodyTemplate  string
+	// Item GUID strategy, GUIDs of already published items are kept if GUIDKeepPrevious is set
+	GUIDStrategy     string
+	GUIDKeepPrevious bool
+	// Title extraction strategies separated by comma, tried in order
+	TitleStrategy  string
+	TitleMaxLength int
+	TitleWords     int
+	// HTML sanitizer policy: preset name or allowlist like "a[href], b, i"
+	HTMLPolicy string
+	// Spoilers and expandable quotes rendering
+	Spoiler            string
+	SpoilerPlaceholder string
+	CollapseQuotes     bool
+	// Render custom emoji as images
+	CustomEmoji bool
+	// Rewriting of t.me links in the post text: native, preview or mirror
+	LinkMode      string
+	LinkMirrorURL string
+	// Remove tracking query params like utm_source from external links
+	StripTracking bool
+	// Actions of post kinds separated by comma, e.g. "service=skip, poll=describe"
+	PostKinds string
+	// Proxy template of image, video and avatar URLs, e.g. https://proxy.example/{url_b64}
+	MediaProxy string
+	// Mark merged feed items with the channel they come from
+	MergeTitlePrefix bool
+	MergeItemSource  bool
+	// Dedup strategies of merged feed items separated by comma
+	MergeDedup string
+	// Merged feed metadata
+	MergedFeedTitle       string
+	MergedFeedDescription string
+	MergedFeedLink        string
+	MergedFeedImage       string // image URL or "collage" to build it from channel avatars
+	MergedFeedLanguage    string
+	MergedFeedCopyright   string
+	// Download post media to the output directory, videos up to the size in megabytes
+	MirrorMedia        bool
+	MirrorMaxVideoSize int
+	// Append channel statistics to <OutputDir>/stats/<channel>.<format>: csv or jsonl, disabled if empty
+	Stats          string
+	StatsSparkline bool
+	// PublicURL is the base URL where output files are published
+	PublicURL string
+	// Record HTTP responses to the directory or replay them from it instead of network requests, set by CLI flags
+	RecordDir string
+	ReplayDir string
 }
 
+const collageImage = "collage"
+
+// doctorCommand runs the parser self-check against the channels instead of building feeds
+const doctorCommand = "doctor"
+
+const defaultMirrorMaxVideoSize = 20
+
 func (c *Config) String() string {
-	return fmt.Sprintf("OutputDir: %s, TelegramChannels: %s, Formats: %s", c.OutputDir, c.TelegramChannels, c.Formats)
+	return fmt.Sprintf("%+v", *c)
+}
+
+// getEnvBool returns the boolean value of the environment variable or default value
+func getEnvBool(name string, defaultValue bool) bool {
+	str := os.Getenv(name)
+	if str == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(str)
+	if err != nil {
+		log.Printf("[ERROR] can't parse %s: %v, fallback with %t", name, err, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvInt returns the integer value of the environment variable or default value
+func getEnvInt(name string, defaultValue int) int {
+	str := os.Getenv(name)
+	if str == "" {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(str)
+	if err != nil {
+		log.Printf("[ERROR] can't parse %s: %v, fallback with %d", name, err, defaultValue)
+		return defaultValue
+	}
+	return value
 }
 
 func getConfig() *Config {
@@ -49,49 +140,361 @@ func getConfig() *Config {
 	formats := strings.Split(formatStr, ",")
 
 	return &Config{
-		OutputDir:        outdir,
-		TelegramChannels: channels,
-		Formats:          formats,
+		OutputDir:          outdir,
+		TelegramChannels:   channels,
+		Formats:            formats,
+		ItemTitleTemplate:  os.Getenv("INPUT_ITEM-TITLE-TEMPLATE"),
+		ItemBodyTemplate:   os.Getenv("INPUT_ITEM-BODY-TEMPLATE"),
+		GUIDStrategy:       os.Getenv("INPUT_GUID-STRATEGY"),
+		GUIDKeepPrevious:   getEnvBool("INPUT_GUID-KEEP-PREVIOUS", false),
+		TitleStrategy:      os.Getenv("INPUT_TITLE-STRATEGY"),
+		TitleMaxLength:     getEnvInt("INPUT_TITLE-MAX-LENGTH", 0),
+		TitleWords:         getEnvInt("INPUT_TITLE-WORDS", 0),
+		HTMLPolicy:         os.Getenv("INPUT_HTML-POLICY"),
+		Spoiler:            os.Getenv("INPUT_SPOILER"),
+		SpoilerPlaceholder: os.Getenv("INPUT_SPOILER-PLACEHOLDER"),
+		CollapseQuotes:     getEnvBool("INPUT_COLLAPSE-QUOTES", false),
+		CustomEmoji:        getEnvBool("INPUT_CUSTOM-EMOJI", false),
+		LinkMode:           os.Getenv("INPUT_LINK-MODE"),
+		LinkMirrorURL:      os.Getenv("INPUT_LINK-MIRROR-URL"),
+		StripTracking:      getEnvBool("INPUT_STRIP-TRACKING", false),
+		MediaProxy:         os.Getenv("INPUT_MEDIA-PROXY"),
+		PostKinds:          os.Getenv("INPUT_POST-KINDS"),
+		MergeTitlePrefix:   getEnvBool("INPUT_MERGE-TITLE-PREFIX", false),
+		MergeItemSource:    getEnvBool("INPUT_MERGE-ITEM-SOURCE", false),
+		MergeDedup:         os.Getenv("INPUT_MERGE-DEDUP"),
+
+		MergedFeedTitle:       os.Getenv("INPUT_MERGED-FEED-TITLE"),
+		MergedFeedDescription: os.Getenv("INPUT_MERGED-FEED-DESCRIPTION"),
+		MergedFeedLink:        os.Getenv("INPUT_MERGED-FEED-LINK"),
+		MergedFeedImage:       os.Getenv("INPUT_MERGED-FEED-IMAGE"),
+		MergedFeedLanguage:    os.Getenv("INPUT_MERGED-FEED-LANGUAGE"),
+		MergedFeedCopyright:   os.Getenv("INPUT_MERGED-FEED-COPYRIGHT"),
+		PublicURL:             os.Getenv("INPUT_PUBLIC-URL"),
+		MirrorMedia:           getEnvBool("INPUT_MIRROR-MEDIA", false),
+		MirrorMaxVideoSize:    getEnvInt("INPUT_MIRROR-MAX-VIDEO-SIZE", defaultMirrorMaxVideoSize),
+		Stats:                 os.Getenv("INPUT_STATS"),
+		StatsSparkline:        getEnvBool("INPUT_STATS-SPARKLINE", false),
 	}
 }
 
-func main() {
-	fmt.Println("Running tg2feed " + revision)
-	cfg := getConfig()
+// getMergeOptions builds merge options from the config, collage is built from the avatars if requested
+func getMergeOptions(cfg *Config, avatars []string, client *http.Client) (feed.MergeOptions, error) {
+	dedup, err := feed.ParseDedupStrategies(cfg.MergeDedup)
+	if err != nil {
+		return feed.MergeOptions{}, err
+	}
 
-	// Print config
-	log.Printf("[INFO] Config: %s", cfg)
+	imageURL := cfg.MergedFeedImage
+	if imageURL == collageImage {
+		imageURL = ""
+		if cfg.PublicURL == "" {
+			log.Print("[ERROR] public URL is required for collage image, skipping")
+		} else if err := feed.SaveCollage(avatars, cfg.OutputDir, client); err != nil {
+			log.Printf("[ERROR] failed to build collage: %v, skipping", err)
+		} else {
+			imageURL = strings.TrimRight(cfg.PublicURL, "/") + "/" + feed.CollageFileName
+		}
+	}
+	return feed.MergeOptions{
+		TitlePrefix: cfg.MergeTitlePrefix,
+		Source:      cfg.MergeItemSource,
+		Dedup:       dedup,
+		Title:       cfg.MergedFeedTitle,
+		Description: cfg.MergedFeedDescription,
+		Link:        cfg.MergedFeedLink,
+		ImageURL:    imageURL,
+		Language:    cfg.MergedFeedLanguage,
+		Copyright:   cfg.MergedFeedCopyright,
+	}, nil
+}
 
-	var tgFeed *feeds.Feed
-	tgFeeds := make([]*feeds.Feed, len(cfg.TelegramChannels))
+// getMirrorOptions builds media mirroring options from the config
+func getMirrorOptions(cfg *Config) (feed.MirrorOptions, error) {
+	if cfg.PublicURL == "" {
+		return feed.MirrorOptions{}, fmt.Errorf("public URL is required for media mirroring")
+	}
+	return feed.MirrorOptions{
+		Dir:          cfg.OutputDir,
+		BaseURL:      cfg.PublicURL,
+		MaxVideoSize: int64(cfg.MirrorMaxVideoSize) << 20,
+	}, nil
+}
+
+// getStatsOptions builds channel statistics options from the config, empty format means statistics are disabled
+func getStatsOptions(cfg *Config) (feed.StatsOptions, error) {
+	format, err := feed.ParseStatsFormat(cfg.Stats)
+	if err != nil {
+		return feed.StatsOptions{}, err
+	}
+	return feed.StatsOptions{
+		Dir:       cfg.OutputDir,
+		Format:    format,
+		Sparkline: cfg.StatsSparkline,
+	}, nil
+}
+
+// getHTTPClient returns the client recording or replaying HTTP responses if requested, nil for default client
+func getHTTPClient(cfg *Config) (*http.Client, error) {
+	switch {
+	case cfg.RecordDir != "" && cfg.ReplayDir != "":
+		return nil, fmt.Errorf("record and replay directories can't be used together")
+	case cfg.RecordDir != "":
+		log.Printf("[INFO] Recording HTTP responses to %s", cfg.RecordDir)
+		return replay.NewClient(cfg.RecordDir, replay.Record), nil
+	case cfg.ReplayDir != "":
+		log.Printf("[INFO] Replaying HTTP responses from %s", cfg.ReplayDir)
+		return replay.NewClient(cfg.ReplayDir, replay.Replay), nil
+	}
+	return nil, nil
+}
+
+// getParserOptions builds parser options from the config
+func getParserOptions(cfg *Config) (parser.Options, error) {
+	strategies, err := parser.ParseTitleStrategies(cfg.TitleStrategy)
+	if err != nil {
+		return parser.Options{}, err
+	}
+	policy, err := parser.ParsePolicy(cfg.HTMLPolicy)
+	if err != nil {
+		return parser.Options{}, err
+	}
+	spoiler, err := parser.ParseSpoilerStrategy(cfg.Spoiler)
+	if err != nil {
+		return parser.Options{}, err
+	}
+	linkMode, err := parser.ParseLinkMode(cfg.LinkMode)
+	if err != nil {
+		return parser.Options{}, err
+	}
+	if linkMode == parser.LinkMirror && cfg.LinkMirrorURL == "" {
+		return parser.Options{}, fmt.Errorf("link mirror URL is required for %s link mode", linkMode)
+	}
+	proxyTemplate, err := parser.ParseProxyTemplate(cfg.MediaProxy)
+	if err != nil {
+		return parser.Options{}, err
+	}
+	kinds, err := parser.ParseKindActions(cfg.PostKinds)
+	if err != nil {
+		return parser.Options{}, err
+	}
+	return parser.Options{
+		Title: parser.TitleOptions{
+			Strategies: strategies,
+			MaxLength:  cfg.TitleMaxLength,
+			Words:      cfg.TitleWords,
+		},
+		Policy: policy,
+		Entities: parser.EntityOptions{
+			Spoiler:            spoiler,
+			SpoilerPlaceholder: cfg.SpoilerPlaceholder,
+			CollapseQuotes:     cfg.CollapseQuotes,
+			CustomEmoji:        cfg.CustomEmoji,
+		},
+		Links: parser.LinkOptions{
+			Mode:          linkMode,
+			MirrorURL:     cfg.LinkMirrorURL,
+			StripTracking: cfg.StripTracking,
+		},
+		Media: parser.MediaOptions{ProxyTemplate: proxyTemplate},
+		Kinds: kinds,
+	}, nil
+}
+
+// getFeedOptions builds feed options from the config
+func getFeedOptions(cfg *Config) (feed.Options, error) {
+	titleTmpl, err := feed.ParseTitleTemplate(cfg.ItemTitleTemplate)
+	if err != nil {
+		return feed.Options{}, fmt.Errorf("can't parse item title template: %w", err)
+	}
+	bodyTmpl, err := feed.ParseBodyTemplate(cfg.ItemBodyTemplate)
+	if err != nil {
+		return feed.Options{}, fmt.Errorf("can't parse item body template: %w", err)
+	}
+	guidStrategy, err := feed.ParseGUIDStrategy(cfg.GUIDStrategy)
+	if err != nil {
+		return feed.Options{}, err
+	}
+	var previousGUIDs map[string]feed.GUID
+	if cfg.GUIDKeepPrevious {
+		previousGUIDs = feed.LoadGUIDs(cfg.OutputDir)
+		log.Printf("[INFO] Loaded %d previous GUIDs", len(previousGUIDs))
+	}
+	return feed.Options{
+		TitleTemplate: titleTmpl,
+		BodyTemplate:  bodyTmpl,
+		GUID:          guidStrategy,
+		PreviousGUIDs: previousGUIDs,
+	}, nil
+}
+
+// describeParseError returns the channel page error with the hint how to fix it
+func describeParseError(err error) string {
+	switch {
+	case errors.Is(err, parser.ErrChannelNotFound):
+		return fmt.Sprintf("%v, check the channel name", err)
+	case errors.Is(err, parser.ErrPreviewDisabled):
+		return fmt.Sprintf("%v, the channel is private or its web preview is turned off", err)
+	case errors.Is(err, parser.ErrRateLimited):
+		return fmt.Sprintf("%v, try again later or request fewer channels", err)
+	case errors.Is(err, parser.ErrMarkupChanged):
+		return fmt.Sprintf("%v, Telegram may have changed the web preview, please report an issue", err)
+	}
+	return err.Error()
+}
+
+// runDoctor checks the parser against the channel pages and prints the reports, reports whether all checks passed
+func runDoctor(channels []string, load func(string) (*goquery.Document, error), w io.Writer) bool {
+	ok := true
+	for _, tgChannel := range channels {
+		fmt.Fprintf(w, "Checking %s\n", tgChannel)
+		doc, err := load(tgChannel)
+		if err != nil {
+			fmt.Fprintf(w, "! %s\n", describeParseError(err))
+			ok = false
+			continue
+		}
+		report := parser.CheckDocument(doc)
+		fmt.Fprint(w, report)
+		if !report.OK() {
+			ok = false
+		}
+	}
+	if ok {
+		fmt.Fprintln(w, "All checks passed")
+	} else {
+		fmt.Fprintln(w, "Some checks failed, Telegram may have changed the web preview markup")
+	}
+	return ok
+}
+
+// run builds the feeds of the channels and saves them to the output directory, requests are made with the client
+func run(cfg *Config, client *http.Client) error {
+	parserOpts, err := getParserOptions(cfg)
+	if err != nil {
+		return err
+	}
+	parserOpts.Client = client
+	feedOpts, err := getFeedOptions(cfg)
+	if err != nil {
+		return err
+	}
+	var mirrorOpts feed.MirrorOptions
+	if cfg.MirrorMedia {
+		if mirrorOpts, err = getMirrorOptions(cfg); err != nil {
+			return err
+		}
+		mirrorOpts.Client = client
+	}
+	statsOpts, err := getStatsOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	var tgFeed *feed.Feed
+	tgFeeds := make([]*feed.Feed, len(cfg.TelegramChannels))
+	avatars := make([]string, len(cfg.TelegramChannels))
 
 	// Build RSS feed for each channel
 	for i, tgChannel := range cfg.TelegramChannels {
 		log.Printf("[INFO] Building RSS feed for Telegram channel: " + tgChannel)
 		// Parse the page
-		page, err := parser.Parse(tgChannel)
+		page, err := parser.Parse(tgChannel, parserOpts)
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+		if cfg.MirrorMedia {
+			if err := feed.MirrorMedia(page, mirrorOpts); err != nil {
+				log.Printf("[ERROR] failed to mirror media of %s: %v, skipping", tgChannel, err)
+			}
 		}
-		tgFeeds[i] = feed.GetFeed(page)
+		if statsOpts.Format != "" {
+			if err := feed.SaveStats(page, statsOpts); err != nil {
+				log.Printf("[ERROR] failed to save stats of %s: %v, skipping", tgChannel, err)
+			}
+		}
+		tgFeeds[i] = feed.GetFeed(page, feedOpts)
+		avatars[i] = page.ImageURL
 	}
 
 	// Merge all feeds if there are more than one
 	if len(tgFeeds) > 1 {
 		// Merge all feeds
-		tgFeed = feed.Merge(tgFeeds)
+		mergeOpts, err := getMergeOptions(cfg, avatars, client)
+		if err != nil {
+			return err
+		}
+		tgFeed = feed.Merge(tgFeeds, mergeOpts)
 		log.Printf("[INFO] Merged %d RSS feeds", len(tgFeeds))
 	} else {
 		tgFeed = tgFeeds[0]
 	}
 
 	if tgFeed == nil {
-		log.Fatal("RSS feed is empty")
+		return errors.New("RSS feed is empty")
 	}
 
 	// Save RSS feed to file
-	err := feed.SaveToFile(tgFeed, cfg.OutputDir, cfg.Formats)
+	return feed.SaveToFile(tgFeed, cfg.OutputDir, cfg.Formats)
+}
+
+// parseArgs parses the command line flags to the config and returns the command, empty for building feeds.
+// Flags are accepted before and after the command, e.g. "doctor --replay-dir dir"
+func parseArgs(args []string, cfg *Config) (string, error) {
+	fs := flag.NewFlagSet("tg2feed", flag.ContinueOnError)
+	fs.StringVar(&cfg.RecordDir, "record-dir", "", "record HTTP responses to the directory")
+	fs.StringVar(&cfg.ReplayDir, "replay-dir", "", "replay HTTP responses recorded to the directory instead of network requests")
+
+	command := ""
+	for {
+		if err := fs.Parse(args); err != nil {
+			return "", err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		if command != "" {
+			return "", fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+		}
+		// Parsing stops at the command, flags after it are parsed on the next iteration
+		command, args = fs.Arg(0), fs.Args()[1:]
+	}
+	if command != "" && command != doctorCommand {
+		return "", fmt.Errorf("unknown command: %s", command)
+	}
+	return command, nil
+}
+
+func main() {
+	fmt.Println("Running tg2feed " + revision)
+	cfg := getConfig()
+	command, err := parseArgs(os.Args[1:], cfg)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	// Print config
+	log.Printf("[INFO] Config: %s", cfg)
+
+	client, err := getHTTPClient(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if command == doctorCommand {
+		load := func(chName string) (*goquery.Document, error) {
+			return parser.LoadDocument(chName, client)
+		}
+		if !runDoctor(cfg.TelegramChannels, load, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := run(cfg, client); err != nil {
+		log.Fatal(describeParseError(err))
+	}
 }
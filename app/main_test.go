@@ -1,8 +1,17 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/PuerkitoBio/goquery"
+	"github.com/kulapard/tg2feed/app/feed"
+	"github.com/kulapard/tg2feed/app/parser"
+	"github.com/kulapard/tg2feed/app/replay"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -12,3 +21,289 @@ func TestGetConfig(t *testing.T) {
 	assert.Equal(t, cfg.TelegramChannels, []string{"@telegram"})
 	assert.Equal(t, cfg.Formats, []string{"rss"})
 }
+
+func TestGetFeedOptions(t *testing.T) {
+	opts, err := getFeedOptions(&Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, opts.TitleTemplate)
+	assert.Nil(t, opts.BodyTemplate)
+
+	opts, err = getFeedOptions(&Config{ItemTitleTemplate: "[{{.Page.Title}}] {{.Post.Title}}", ItemBodyTemplate: "{{.Text}}"})
+	assert.Nil(t, err)
+	assert.NotNil(t, opts.TitleTemplate)
+	assert.NotNil(t, opts.BodyTemplate)
+
+	_, err = getFeedOptions(&Config{ItemTitleTemplate: "{{.Post.Title"})
+	assert.NotNil(t, err)
+
+	_, err = getFeedOptions(&Config{ItemBodyTemplate: "{{.Text"})
+	assert.NotNil(t, err)
+
+	opts, err = getFeedOptions(&Config{GUIDStrategy: "tag", GUIDKeepPrevious: true, OutputDir: t.TempDir()})
+	assert.Nil(t, err)
+	assert.Equal(t, feed.GUIDTag, opts.GUID)
+	assert.Equal(t, map[string]feed.GUID{}, opts.PreviousGUIDs)
+
+	_, err = getFeedOptions(&Config{GUIDStrategy: "unknown"})
+	assert.NotNil(t, err)
+}
+
+func TestGetParserOptions(t *testing.T) {
+	opts, err := getParserOptions(&Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, opts.Title.Strategies)
+
+	opts, err = getParserOptions(&Config{TitleStrategy: "bold,first-sentence", TitleMaxLength: 50, TitleWords: 5})
+	assert.Nil(t, err)
+	assert.Equal(t, []parser.TitleStrategy{parser.TitleBold, parser.TitleFirstSentence}, opts.Title.Strategies)
+	assert.Equal(t, 50, opts.Title.MaxLength)
+	assert.Equal(t, 5, opts.Title.Words)
+
+	_, err = getParserOptions(&Config{TitleStrategy: "unknown"})
+	assert.NotNil(t, err)
+
+	opts, err = getParserOptions(&Config{HTMLPolicy: "formatting"})
+	assert.Nil(t, err)
+	assert.Equal(t, parser.FormattingPolicy(), opts.Policy)
+
+	_, err = getParserOptions(&Config{HTMLPolicy: "a[href"})
+	assert.NotNil(t, err)
+
+	opts, err = getParserOptions(&Config{Spoiler: "placeholder", SpoilerPlaceholder: "***", CollapseQuotes: true, CustomEmoji: true})
+	assert.Nil(t, err)
+	assert.Equal(t, parser.EntityOptions{Spoiler: parser.SpoilerPlaceholder, SpoilerPlaceholder: "***", CollapseQuotes: true, CustomEmoji: true}, opts.Entities)
+
+	_, err = getParserOptions(&Config{Spoiler: "unknown"})
+	assert.NotNil(t, err)
+
+	opts, err = getParserOptions(&Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, parser.LinkOptions{Mode: parser.LinkPreview}, opts.Links)
+
+	opts, err = getParserOptions(&Config{LinkMode: "mirror", LinkMirrorURL: "https://mirror.example.com", StripTracking: true})
+	assert.Nil(t, err)
+	assert.Equal(t, parser.LinkOptions{Mode: parser.LinkMirror, MirrorURL: "https://mirror.example.com", StripTracking: true}, opts.Links)
+
+	_, err = getParserOptions(&Config{LinkMode: "mirror"})
+	assert.NotNil(t, err)
+
+	_, err = getParserOptions(&Config{LinkMode: "unknown"})
+	assert.NotNil(t, err)
+
+	opts, err = getParserOptions(&Config{MediaProxy: "https://proxy.example/{url_b64}"})
+	assert.Nil(t, err)
+	assert.Equal(t, parser.MediaOptions{ProxyTemplate: "https://proxy.example/{url_b64}"}, opts.Media)
+
+	_, err = getParserOptions(&Config{MediaProxy: "https://proxy.example/"})
+	assert.NotNil(t, err)
+
+	opts, err = getParserOptions(&Config{PostKinds: "service=skip, sticker=describe"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[parser.PostKind]parser.KindAction{parser.KindService: parser.KindSkip, parser.KindSticker: parser.KindDescribe}, opts.Kinds)
+
+	_, err = getParserOptions(&Config{PostKinds: "service"})
+	assert.NotNil(t, err)
+}
+
+func TestGetEnvInt(t *testing.T) {
+	assert.Equal(t, 30, getEnvInt("INPUT_TEST-INT", 30))
+
+	t.Setenv("INPUT_TEST-INT", "50")
+	assert.Equal(t, 50, getEnvInt("INPUT_TEST-INT", 30))
+
+	t.Setenv("INPUT_TEST-INT", "wrong")
+	assert.Equal(t, 30, getEnvInt("INPUT_TEST-INT", 30))
+}
+
+func TestGetEnvBool(t *testing.T) {
+	assert.Equal(t, false, getEnvBool("INPUT_TEST-BOOL", false))
+
+	t.Setenv("INPUT_TEST-BOOL", "true")
+	assert.Equal(t, true, getEnvBool("INPUT_TEST-BOOL", false))
+
+	t.Setenv("INPUT_TEST-BOOL", "wrong")
+	assert.Equal(t, true, getEnvBool("INPUT_TEST-BOOL", true))
+}
+
+func TestGetMergeOptions(t *testing.T) {
+	cfg := &Config{
+		MergeTitlePrefix:      true,
+		MergedFeedTitle:       "My Feed",
+		MergedFeedDescription: "My channels",
+		MergedFeedLink:        "https://example.com",
+		MergedFeedImage:       "https://example.com/logo.png",
+		MergedFeedLanguage:    "en",
+		MergedFeedCopyright:   "CC BY 4.0",
+	}
+	opts, err := getMergeOptions(cfg, nil, nil)
+	assert.Nil(t, err)
+	assert.True(t, opts.TitlePrefix)
+	assert.False(t, opts.Source)
+	assert.Equal(t, "My Feed", opts.Title)
+	assert.Equal(t, "My channels", opts.Description)
+	assert.Equal(t, "https://example.com", opts.Link)
+	assert.Equal(t, "https://example.com/logo.png", opts.ImageURL)
+	assert.Equal(t, "en", opts.Language)
+	assert.Equal(t, "CC BY 4.0", opts.Copyright)
+
+	// Collage requires public URL
+	opts, err = getMergeOptions(&Config{MergedFeedImage: "collage"}, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "", opts.ImageURL)
+
+	// Collage can't be built without avatars
+	opts, err = getMergeOptions(&Config{MergedFeedImage: "collage", PublicURL: "https://example.com/", OutputDir: t.TempDir()}, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "", opts.ImageURL)
+
+	// Dedup strategies
+	opts, err = getMergeOptions(&Config{MergeDedup: "forward,text"}, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []feed.DedupStrategy{feed.DedupForward, feed.DedupText}, opts.Dedup)
+
+	_, err = getMergeOptions(&Config{MergeDedup: "unknown"}, nil, nil)
+	assert.NotNil(t, err)
+}
+
+func TestGetMirrorOptions(t *testing.T) {
+	_, err := getMirrorOptions(&Config{MirrorMedia: true})
+	assert.NotNil(t, err)
+
+	opts, err := getMirrorOptions(&Config{MirrorMedia: true, MirrorMaxVideoSize: 5, OutputDir: "out", PublicURL: "https://example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, feed.MirrorOptions{Dir: "out", BaseURL: "https://example.com", MaxVideoSize: 5 << 20}, opts)
+}
+
+func TestGetStatsOptions(t *testing.T) {
+	opts, err := getStatsOptions(&Config{OutputDir: "out"})
+	assert.Nil(t, err)
+	assert.Equal(t, feed.StatsFormat(""), opts.Format)
+
+	opts, err = getStatsOptions(&Config{OutputDir: "out", Stats: "jsonl", StatsSparkline: true})
+	assert.Nil(t, err)
+	assert.Equal(t, feed.StatsOptions{Dir: "out", Format: feed.StatsJSONL, Sparkline: true}, opts)
+
+	_, err = getStatsOptions(&Config{Stats: "xml"})
+	assert.NotNil(t, err)
+}
+
+func TestDescribeParseError(t *testing.T) {
+	err := fmt.Errorf("can't parse unknown: %w", parser.ErrChannelNotFound)
+	assert.Equal(t, "can't parse unknown: channel not found, check the channel name", describeParseError(err))
+	assert.Contains(t, describeParseError(parser.ErrPreviewDisabled), "private")
+	assert.Contains(t, describeParseError(parser.ErrRateLimited), "try again later")
+	assert.Contains(t, describeParseError(parser.ErrMarkupChanged), "report an issue")
+	assert.Equal(t, "other", describeParseError(errors.New("other")))
+}
+
+func TestRunDoctor(t *testing.T) {
+	pages := map[string]string{
+		"good": `<div class="tgme_channel_info_header_title">Title</div>
+<div class="tgme_channel_info_header_username"><a href="https://t.me/good">@good</a></div>
+<div class="tgme_widget_message_wrap"><div class="tgme_widget_message" data-post="good/1">
+<a class="tgme_widget_message_date" href="https://t.me/good/1"><time datetime="2023-12-15T16:29:45+00:00"></time></a>
+</div></div>`,
+		"empty": `<div class="tgme_channel_info_header_title">Title</div>`,
+	}
+	load := func(name string) (*goquery.Document, error) {
+		page, ok := pages[name]
+		if !ok {
+			return nil, fmt.Errorf("can't load %s: %w", name, parser.ErrChannelNotFound)
+		}
+		return goquery.NewDocumentFromReader(strings.NewReader(page))
+	}
+
+	var out strings.Builder
+	assert.True(t, runDoctor([]string{"good"}, load, &out))
+	assert.Contains(t, out.String(), "Checking good\n")
+	assert.Contains(t, out.String(), "+ .tgme_widget_message_wrap: expected >= 1, found 1\n")
+	assert.True(t, strings.HasSuffix(out.String(), "All checks passed\n"))
+
+	out.Reset()
+	assert.False(t, runDoctor([]string{"good", "empty", "missing"}, load, &out))
+	assert.Contains(t, out.String(), "- .tgme_widget_message_wrap: expected >= 1, found 0\n")
+	assert.Contains(t, out.String(), "? .tgme_widget_message_views: expected any, found 0\n! no posts found\n")
+	assert.Contains(t, out.String(), "! can't load missing: channel not found, check the channel name\n")
+	assert.True(t, strings.HasSuffix(out.String(), "Some checks failed, Telegram may have changed the web preview markup\n"))
+}
+
+func TestParseArgs(t *testing.T) {
+	tbl := []struct {
+		args      []string
+		command   string
+		replayDir string
+		recordDir string
+		err       bool
+	}{
+		{nil, "", "", "", false},
+		{[]string{"--record-dir", "out"}, "", "", "out", false},
+		{[]string{"doctor"}, "doctor", "", "", false},
+		// Flags before and after the command
+		{[]string{"doctor", "--replay-dir", "testdata/replay"}, "doctor", "testdata/replay", "", false},
+		{[]string{"--replay-dir=testdata/replay", "doctor"}, "doctor", "testdata/replay", "", false},
+		{[]string{"unknown"}, "", "", "", true},
+		{[]string{"doctor", "extra"}, "", "", "", true},
+		{[]string{"doctor", "--wrong"}, "", "", "", true},
+	}
+	for _, tb := range tbl {
+		cfg := &Config{}
+		command, err := parseArgs(tb.args, cfg)
+		assert.Equal(t, tb.err, err != nil, tb.args)
+		assert.Equal(t, tb.command, command, tb.args)
+		if !tb.err {
+			assert.Equal(t, tb.replayDir, cfg.ReplayDir, tb.args)
+			assert.Equal(t, tb.recordDir, cfg.RecordDir, tb.args)
+		}
+	}
+}
+
+func TestGetHTTPClient(t *testing.T) {
+	client, err := getHTTPClient(&Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, client)
+
+	client, err = getHTTPClient(&Config{ReplayDir: "testdata/replay"})
+	assert.Nil(t, err)
+	assert.Equal(t, &replay.Transport{Dir: "testdata/replay", Mode: replay.Replay}, client.Transport)
+
+	client, err = getHTTPClient(&Config{RecordDir: "out"})
+	assert.Nil(t, err)
+	assert.Equal(t, &replay.Transport{Dir: "out", Mode: replay.Record}, client.Transport)
+
+	_, err = getHTTPClient(&Config{RecordDir: "out", ReplayDir: "testdata/replay"})
+	assert.NotNil(t, err)
+}
+
+func TestRun_Replay(t *testing.T) {
+	client, err := getHTTPClient(&Config{ReplayDir: "testdata/replay"})
+	assert.Nil(t, err)
+
+	dir := t.TempDir()
+	cfg := &Config{OutputDir: dir, TelegramChannels: []string{"@corpus"}, Formats: []string{"rss", "json"}}
+	assert.Nil(t, run(cfg, client))
+
+	rss, err := os.ReadFile(filepath.Join(dir, "rss.xml"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(rss), "<title>Test Corpus</title>")
+	assert.Contains(t, string(rss), "<link>https://t.me/s/corpus/110</link>")
+	assert.Contains(t, string(rss), "<tg:subscribers>48200</tg:subscribers>")
+	_, err = os.Stat(filepath.Join(dir, "feed.json"))
+	assert.Nil(t, err)
+
+	// Private channel redirects to the channel info page
+	cfg.TelegramChannels = []string{"private"}
+	err = run(cfg, client)
+	assert.True(t, errors.Is(err, parser.ErrPreviewDisabled), err)
+	assert.Contains(t, describeParseError(err), "redirected to https://t.me/private")
+
+	// Channel was not recorded
+	cfg.TelegramChannels = []string{"unknown"}
+	err = run(cfg, client)
+	assert.True(t, errors.Is(err, replay.ErrNotRecorded), err)
+
+	var out strings.Builder
+	load := func(chName string) (*goquery.Document, error) {
+		return parser.LoadDocument(chName, client)
+	}
+	assert.True(t, runDoctor([]string{"corpus"}, load, &out), out.String())
+}
@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Telegram media markup, messages of the media group are rendered as grouped wrap or separate media messages.
+// Videos are matched by the player only, e.g. video stickers are not album media.
+const albumMediaSelector = ".tgme_widget_message_grouped_wrap, .tgme_widget_message_photo_wrap, .tgme_widget_message_video_player"
+
+// Grouped wrap of the media group, its media links to the messages of the group, e.g. https://t.me/telegram/2?single
+const (
+	groupedWrapSelector  = ".tgme_widget_message_grouped_wrap"
+	groupedMediaSelector = groupedWrapSelector + " a[href]"
+)
+
+// albumGroup collects consecutive messages of the same media group into the first message post
+type albumGroup struct {
+	post *Post
+	// s is the selection of all the group messages
+	s *goquery.Selection
+	// lastMessageID is the message ID of the last message in the group
+	lastMessageID int
+	// groupedIDs are the message IDs linked from the grouped wrap of the group
+	groupedIDs []int
+}
+
+func newAlbumGroup(s *goquery.Selection, post *Post) *albumGroup {
+	return &albumGroup{post: post, s: s, lastMessageID: post.MessageID, groupedIDs: getGroupedMessageIDs(s, post.Channel)}
+}
+
+// accepts reports whether the message belongs to the group of the same channel: the message is linked
+// from the grouped wrap of the group, or both are separate media messages with adjacent IDs and the same timestamp
+// and only one of them has caption. Grouped wrap is the complete media group, it's not merged with other messages.
+func (g *albumGroup) accepts(s *goquery.Selection, post *Post) bool {
+	if g.post.Channel == "" || post.Channel != g.post.Channel {
+		return false
+	}
+	if slices.Contains(g.groupedIDs, post.MessageID) {
+		return true
+	}
+	return post.MessageID == g.lastMessageID+1 &&
+		post.Created.Equal(g.post.Created) &&
+		isSeparateMediaMessage(g.s) && isSeparateMediaMessage(s) &&
+		(g.post.Text == "" || post.Text == "")
+}
+
+// add merges the message post into the group post
+func (g *albumGroup) add(s *goquery.Selection, post *Post, opts Options) {
+	g.s = g.s.AddSelection(s)
+	g.lastMessageID = post.MessageID
+
+	p := g.post
+	p.Images = appendNew(p.Images, post.Images...)
+	p.Videos = appendNew(p.Videos, post.Videos...)
+	p.VideoThumbs = appendNew(p.VideoThumbs, post.VideoThumbs...)
+	if p.Sticker == nil {
+		p.Sticker = post.Sticker
+	}
+	if p.ForwardedFrom == "" {
+		p.ForwardedFrom = post.ForwardedFrom
+	}
+	// Caption can be on any message of the group
+	if p.Text == "" {
+		p.Text = post.Text
+		p.Markdown = post.Markdown
+		p.PlainText = post.PlainText
+	}
+	p.Title = BuildPostTitle(g.s, p.Text, opts.Title)
+}
+
+// isSeparateMediaMessage reports whether the messages have media and none of them has grouped wrap
+func isSeparateMediaMessage(s *goquery.Selection) bool {
+	return s.Find(albumMediaSelector).Length() > 0 && s.Find(groupedWrapSelector).Length() == 0
+}
+
+// getGroupedMessageIDs returns the IDs of the channel messages linked from the grouped wrap media
+func getGroupedMessageIDs(s *goquery.Selection, channel string) []int {
+	var ids []int
+	s.Find(groupedMediaSelector).Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		u, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+		ch, id, err := ParsePostID(strings.TrimPrefix(u.Path, "/"))
+		if err == nil && ch == channel {
+			ids = append(ids, id)
+		}
+	})
+	return ids
+}
+
+// appendNew appends the values not yet in the slice
+func appendNew(values []string, newValues ...string) []string {
+	for _, v := range newValues {
+		if !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
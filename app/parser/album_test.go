@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// getAlbumMessageHTML returns the message HTML with the photo and optional caption
+func getAlbumMessageHTML(id int, datetime, caption string) string {
+	text := ""
+	if caption != "" {
+		text = `<div class="tgme_widget_message_text">` + caption + `</div>`
+	}
+	return fmt.Sprintf(`<div class="tgme_widget_message_wrap"><div class="tgme_widget_message" data-post="telegram/%d">
+		<a class="tgme_widget_message_photo_wrap" style="background-image:url('https://cdn.example.com/%d.jpg')"></a>%s
+		<a class="tgme_widget_message_date"><time datetime="%s"></time></a>
+	</div></div>`, id, id, text, datetime)
+}
+
+func TestGetPosts_Album(t *testing.T) {
+	const date1 = "2023-12-15T16:29:45+00:00"
+	const date2 = "2023-12-15T17:00:00+00:00"
+	html := "<body>" +
+		getAlbumMessageHTML(1, date1, "") +
+		getAlbumMessageHTML(2, date1, "Album caption") +
+		getAlbumMessageHTML(3, date1, "") +
+		// Next message has different timestamp
+		getAlbumMessageHTML(4, date2, "") +
+		// Next message is not adjacent
+		getAlbumMessageHTML(6, date2, "") +
+		// Both messages have captions
+		getAlbumMessageHTML(10, date2, "Caption 10") +
+		getAlbumMessageHTML(11, date2, "Caption 11") +
+		"</body>"
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	posts := GetPosts(doc, Options{Title: TitleOptions{Strategies: []TitleStrategy{TitleMedia}}})
+	var ids []int
+	for _, post := range posts {
+		ids = append(ids, post.MessageID)
+	}
+	assert.Equal(t, []int{1, 4, 6, 10, 11}, ids)
+
+	album := posts[0]
+	assert.Equal(t, "https://t.me/s/telegram/1", album.Link)
+	assert.Equal(t, "<p>Album caption</p>", album.Text)
+	assert.Equal(t, "Album caption", album.PlainText)
+	assert.Equal(t, "Photo album (3)", album.Title)
+	assert.Equal(t, []string{"https://cdn.example.com/1.jpg", "https://cdn.example.com/2.jpg", "https://cdn.example.com/3.jpg"}, album.Images)
+
+	assert.Equal(t, "Photo", posts[1].Title)
+	assert.Equal(t, []string{"https://cdn.example.com/10.jpg"}, posts[3].Images)
+	assert.Equal(t, "<p>Caption 11</p>", posts[4].Text)
+}
+
+func TestGetPosts_AlbumGrouped(t *testing.T) {
+	const date = "2023-12-15T16:29:45+00:00"
+	const grouped = `<div class="tgme_widget_message_wrap"><div class="tgme_widget_message" data-post="telegram/20">
+		<div class="tgme_widget_message_grouped_wrap">
+			<a class="tgme_widget_message_photo_wrap grouped_media_wrap" href="https://t.me/telegram/20?single"
+				style="background-image:url('https://cdn.example.com/20.jpg')"></a>
+			<a class="tgme_widget_message_photo_wrap grouped_media_wrap" href="https://t.me/telegram/21?single"
+				style="background-image:url('https://cdn.example.com/21.jpg')"></a>
+		</div>
+		<a class="tgme_widget_message_date"><time datetime="` + date + `"></time></a>
+	</div></div>`
+	const videoSticker = `<div class="tgme_widget_message_wrap"><div class="tgme_widget_message" data-post="telegram/31">
+		<div class="tgme_widget_message_videosticker"><video src="https://cdn.example.com/sticker.webm"></video></div>
+		<a class="tgme_widget_message_date"><time datetime="` + date + `"></time></a>
+	</div></div>`
+	html := "<body>" + grouped +
+		// Message linked from the grouped wrap is merged even with the caption
+		getAlbumMessageHTML(21, date, "Caption") +
+		// Separate media message next to the complete media group is not merged
+		getAlbumMessageHTML(22, date, "") +
+		// Video sticker is not album media
+		getAlbumMessageHTML(30, date, "") + videoSticker +
+		"</body>"
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	posts := GetPosts(doc, Options{})
+	var ids []int
+	for _, post := range posts {
+		ids = append(ids, post.MessageID)
+	}
+	assert.Equal(t, []int{20, 22, 30, 31}, ids)
+	assert.Equal(t, []string{"https://cdn.example.com/20.jpg", "https://cdn.example.com/21.jpg"}, posts[0].Images)
+	assert.Equal(t, "<p>Caption</p>", posts[0].Text)
+}
+
+func TestAppendNew(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, appendNew([]string{"a", "b"}, "b", "c", "a"))
+	assert.Equal(t, []string{"a"}, appendNew(nil, "a", "a"))
+}
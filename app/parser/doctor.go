@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+)
+
+// SelectorCheck is the number of elements found by the selector the parser relies on
+type SelectorCheck struct {
+	Selector string
+	// Required selectors must match at least one element, others are reported only
+	Required bool
+	Found    int
+}
+
+// Report is the result of the parser self-check
+type Report struct {
+	Selectors []SelectorCheck
+	// Problems are the broken invariants, e.g. post without link
+	Problems []string
+}
+
+// Selectors of the page the parser relies on, checked by CheckDocument
+var doctorSelectors = []struct {
+	selector string
+	required bool
+}{
+	{".tgme_channel_info_header_title", true},
+	{".tgme_channel_info_header_username a[href]", true},
+	{".tgme_channel_info_description", false},
+	{".tgme_page_photo_image img[src]", false},
+	{".tgme_channel_info_counter .counter_value", false},
+	{".tgme_widget_message_wrap", true},
+	{".tgme_widget_message[data-post]", true},
+	{".tgme_widget_message_date[href]", true},
+	{".tgme_widget_message_date time[datetime]", true},
+	{".tgme_widget_message_text", false},
+	{".tgme_widget_message_views", false},
+}
+
+// CheckDocument runs the parser against the channel web preview document and checks its invariants:
+// expected selectors are found, there are posts and every post has link, ID and datetime
+func CheckDocument(doc *goquery.Document) *Report {
+	report := &Report{}
+	if err := GetPageError(doc, doc.Url); err != nil {
+		report.Problems = append(report.Problems, err.Error())
+	}
+	for _, sel := range doctorSelectors {
+		check := SelectorCheck{Selector: sel.selector, Required: sel.required, Found: doc.Find(sel.selector).Length()}
+		report.Selectors = append(report.Selectors, check)
+	}
+
+	posts := doc.Find(".tgme_widget_message_wrap")
+	if posts.Length() == 0 {
+		report.Problems = append(report.Problems, "no posts found")
+	}
+	posts.Each(func(i int, s *goquery.Selection) {
+		name := fmt.Sprintf("post #%d", i+1)
+		postID := GetPostID(s)
+		if postID != "" {
+			name = "post " + postID
+		}
+		if _, _, err := ParsePostID(postID); err != nil {
+			report.Problems = append(report.Problems, name+": "+err.Error())
+		}
+		if GetPostLink(s) == "" {
+			report.Problems = append(report.Problems, name+": no link")
+		}
+		// Checked without GetPostCreated to catch its fallback with time.Now()
+		created, _ := s.Find(".tgme_widget_message_date time").Attr("datetime")
+		if _, err := ParseDateTime(created); err != nil {
+			report.Problems = append(report.Problems, name+": "+err.Error())
+		}
+	})
+	return report
+}
+
+// OK reports whether all required selectors are found and there are no problems
+func (r *Report) OK() bool {
+	for _, check := range r.Selectors {
+		if check.Required && check.Found == 0 {
+			return false
+		}
+	}
+	return len(r.Problems) == 0
+}
+
+// String returns the report with expected vs found selectors and the problems
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, check := range r.Selectors {
+		// Missing required selector is the error, missing optional one may be fine, e.g. channel without avatar
+		mark, expected := "+", "any"
+		if check.Required {
+			expected = ">= 1"
+		}
+		if check.Found == 0 {
+			mark = "?"
+			if check.Required {
+				mark = "-"
+			}
+		}
+		fmt.Fprintf(&b, "%s %s: expected %s, found %d\n", mark, check.Selector, expected, check.Found)
+	}
+	for _, problem := range r.Problems {
+		fmt.Fprintf(&b, "! %s\n", problem)
+	}
+	return b.String()
+}
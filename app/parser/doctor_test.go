@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func getDoctorDocument(html string) *goquery.Document {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+func TestCheckDocument(t *testing.T) {
+	report := CheckDocument(getDoctorDocument(testPageHTML + testPostHTML))
+	assert.True(t, report.OK(), report.String())
+	assert.Empty(t, report.Problems)
+	assert.Contains(t, report.String(), "+ .tgme_widget_message_wrap: expected >= 1, found 1\n")
+	assert.Contains(t, report.String(), "+ .tgme_channel_info_description: expected any, found 1\n")
+}
+
+func TestCheckDocument_Broken(t *testing.T) {
+	// Renamed message classes
+	html := strings.ReplaceAll(testPageHTML+testPostHTML, "tgme_widget_message_wrap", "tgme_widget_msg_wrap")
+	report := CheckDocument(getDoctorDocument(html))
+	assert.False(t, report.OK())
+	assert.Equal(t, []string{"no posts found"}, report.Problems)
+	assert.Contains(t, report.String(), "- .tgme_widget_message_wrap: expected >= 1, found 0\n")
+	assert.Contains(t, report.String(), "! no posts found\n")
+
+	// Post without datetime
+	html = testPageHTML + `<div class="tgme_widget_message_wrap"><div class="tgme_widget_message" data-post="telegram/5"></div></div>`
+	report = CheckDocument(getDoctorDocument(html))
+	assert.False(t, report.OK())
+	assert.Equal(t, []string{"post telegram/5: can't parse empty datetime"}, report.Problems)
+
+	// Post without ID and link
+	html = testPageHTML + `<div class="tgme_widget_message_wrap"><div class="tgme_widget_message"></div></div>`
+	report = CheckDocument(getDoctorDocument(html))
+	assert.Len(t, report.Problems, 3)
+	assert.Equal(t, "post #1: no link", report.Problems[1])
+
+	// Not a channel page
+	report = CheckDocument(getDoctorDocument(`<div class="tgme_page"></div>`))
+	assert.False(t, report.OK())
+	assert.Equal(t, []string{"unexpected page markup: channel header not found", "no posts found"}, report.Problems)
+	assert.Contains(t, report.String(), "- .tgme_channel_info_header_title: expected >= 1, found 0\n")
+}
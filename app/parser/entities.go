@@ -0,0 +1,128 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/atom"
+	"html"
+	"strings"
+)
+
+// SpoilerStrategy defines how spoilers are rendered
+type SpoilerStrategy string
+
+// Supported spoiler strategies
+const (
+	// SpoilerText renders spoiler as plain text
+	SpoilerText SpoilerStrategy = "text"
+	// SpoilerDetails hides spoiler in collapsed <details> element
+	SpoilerDetails SpoilerStrategy = "details"
+	// SpoilerPlaceholder replaces spoiler with the placeholder text
+	SpoilerPlaceholder SpoilerStrategy = "placeholder"
+)
+
+const defaultSpoilerPlaceholder = "[spoiler]"
+
+// spoilerClass marks <details> of the spoilers rendered with details strategy, their content is hidden in text renderings
+const spoilerClass = "spoiler"
+
+// Telegram spoiler markup
+const spoilerSelector = "tg-spoiler, .tg-spoiler"
+
+// Telegram expandable blockquote markup
+const expandableQuoteSelector = "blockquote[expandable], blockquote.expandable"
+
+// EntityOptions defines how Telegram entities are rendered
+type EntityOptions struct {
+	// Spoiler strategy, spoilers are hidden behind the placeholder if not set
+	Spoiler SpoilerStrategy
+	// SpoilerPlaceholder replaces spoilers with placeholder strategy, "[spoiler]" if not set
+	SpoilerPlaceholder string
+	// CollapseQuotes renders expandable blockquotes collapsed in <details> element
+	CollapseQuotes bool
+	// CustomEmoji renders custom emoji as images, emoji text is used if not set
+	CustomEmoji bool
+}
+
+// ParseSpoilerStrategy parses the spoiler strategy name
+func ParseSpoilerStrategy(str string) (SpoilerStrategy, error) {
+	strategy := SpoilerStrategy(strings.TrimSpace(str))
+	switch strategy {
+	case "":
+		return SpoilerPlaceholder, nil
+	case SpoilerText, SpoilerDetails, SpoilerPlaceholder:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown spoiler strategy: %s", str)
+}
+
+// tags returns the tags and their attributes produced by the entities rendering
+func (o EntityOptions) tags() map[string][]string {
+	tags := make(map[string][]string)
+	if o.Spoiler == SpoilerDetails || o.CollapseQuotes {
+		// Class marks the spoilers, see spoilerClass
+		tags["details"] = []string{"class"}
+		tags["summary"] = nil
+	}
+	if o.CustomEmoji {
+		tags["img"] = []string{"src", "alt", "width", "height"}
+	}
+	return tags
+}
+
+// FixEntities renders Telegram entities: spoilers, expandable quotes and text formatting
+func FixEntities(s *goquery.Selection, opts EntityOptions) *goquery.Selection {
+	// Clone the selection to avoid modifying the original
+	s = s.Clone()
+
+	// Use the same tags for the same formatting
+	renameTags(s, "strong", "b")
+	renameTags(s, "em", "i")
+	renameTags(s, "ins", "u")
+	renameTags(s, "strike, del", "s")
+
+	s.Find(spoilerSelector).Each(func(_ int, s *goquery.Selection) {
+		switch opts.Spoiler {
+		case SpoilerText:
+			s.ReplaceWithSelection(s.Contents())
+		case SpoilerDetails:
+			wrapWithDetails(s.Contents(), "Spoiler")
+			s.Contents().First().SetAttr("class", spoilerClass)
+			s.ReplaceWithSelection(s.Contents())
+		default:
+			placeholder := opts.SpoilerPlaceholder
+			if placeholder == "" {
+				placeholder = defaultSpoilerPlaceholder
+			}
+			s.ReplaceWithHtml(html.EscapeString(placeholder))
+		}
+	})
+
+	if opts.CollapseQuotes {
+		s.Find(expandableQuoteSelector).Each(func(_ int, s *goquery.Selection) {
+			s.RemoveAttr("expandable")
+			s.RemoveAttr("class")
+			wrapWithDetails(s, "Quote")
+		})
+	}
+	return s
+}
+
+// wrapWithDetails wraps the nodes with collapsed <details> element with the summary
+func wrapWithDetails(s *goquery.Selection, summary string) {
+	if s.Length() == 0 {
+		return
+	}
+	s.First().BeforeHtml("<details><summary>" + html.EscapeString(summary) + "</summary></details>")
+	s.First().Prev().AppendSelection(s)
+}
+
+// renameTags replaces the tags matching the selector with the new tag keeping the content
+func renameTags(s *goquery.Selection, selector, tag string) {
+	s.Find(selector).Each(func(_ int, s *goquery.Selection) {
+		node := s.Get(0)
+		node.Data = tag
+		node.DataAtom = atom.Lookup([]byte(tag))
+		node.Attr = nil
+	})
+}
@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseSpoilerStrategy(t *testing.T) {
+	tbl := []struct {
+		inp string
+		out SpoilerStrategy
+		err error
+	}{
+		{"", SpoilerPlaceholder, nil},
+		{"text", SpoilerText, nil},
+		{"details", SpoilerDetails, nil},
+		{" placeholder ", SpoilerPlaceholder, nil},
+		{"unknown", "", fmt.Errorf("unknown spoiler strategy: unknown")},
+	}
+	for _, tb := range tbl {
+		strategy, err := ParseSpoilerStrategy(tb.inp)
+		assert.Equal(t, tb.err, err)
+		assert.Equal(t, tb.out, strategy)
+	}
+}
+
+func TestFixEntities(t *testing.T) {
+	tbl := []struct {
+		html string
+		opts EntityOptions
+		out  string
+	}{
+		{`<strong>b</strong><em>i</em><ins>u</ins><strike>s</strike><del class="x">s</del>`, EntityOptions{},
+			`<b>b</b><i>i</i><u>u</u><s>s</s><s>s</s>`},
+		{`a <tg-spoiler>secret <b>text</b></tg-spoiler> b`, EntityOptions{},
+			`a [spoiler] b`},
+		{`a <tg-spoiler>secret <b>text</b></tg-spoiler> b`, EntityOptions{Spoiler: SpoilerText},
+			`a secret <b>text</b> b`},
+		{`a <span class="tg-spoiler">secret <b>text</b></span> b`, EntityOptions{Spoiler: SpoilerDetails},
+			`a <details class="spoiler"><summary>Spoiler</summary>secret <b>text</b></details> b`},
+		{`a <tg-spoiler>secret</tg-spoiler> b`, EntityOptions{Spoiler: SpoilerPlaceholder},
+			`a [spoiler] b`},
+		{`a <tg-spoiler>secret</tg-spoiler> b`, EntityOptions{Spoiler: SpoilerPlaceholder, SpoilerPlaceholder: "<hidden>"},
+			`a &lt;hidden&gt; b`},
+		{`<tg-spoiler></tg-spoiler>`, EntityOptions{Spoiler: SpoilerDetails}, ``},
+		{`<blockquote class="expandable">long quote</blockquote><blockquote>quote</blockquote>`, EntityOptions{},
+			`<blockquote class="expandable">long quote</blockquote><blockquote>quote</blockquote>`},
+		{`<blockquote expandable="">long quote</blockquote><blockquote>quote</blockquote>`, EntityOptions{CollapseQuotes: true},
+			`<details><summary>Quote</summary><blockquote>long quote</blockquote></details><blockquote>quote</blockquote>`},
+	}
+	for _, tb := range tbl {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader("<body>" + tb.html + "</body>"))
+		assert.Nil(t, err)
+
+		body := doc.Find("body")
+		fixedBody := FixEntities(body, tb.opts)
+		assert.NotEqualf(t, body, fixedBody, "body and fixedBody should not be equal")
+
+		fixedBodyHTML, err := fixedBody.Html()
+		assert.Nil(t, err)
+		assert.Equal(t, tb.out, fixedBodyHTML)
+	}
+}
+
+func TestGetSafeHTML_Entities(t *testing.T) {
+	const html = `<body><b>Title</b> <tg-spoiler>secret</tg-spoiler> <u>u</u> <s>s</s></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+	body := doc.Find("body")
+
+	// Spoiler markup is kept by strict policy
+	assert.Equal(t, `<b>Title</b> <details class="spoiler"><summary>Spoiler</summary>secret</details> <u>u</u> <s>s</s>`,
+		GetSafeHTML(body, Options{Entities: EntityOptions{Spoiler: SpoilerDetails}}))
+	assert.Equal(t, `<b>Title</b> <details class="spoiler"><summary>Spoiler</summary>secret</details> <u>u</u> <s>s</s>`,
+		GetSafeHTML(body, Options{Policy: FormattingPolicy(), Entities: EntityOptions{Spoiler: SpoilerDetails}}))
+	// Spoiler class is kept by the policy allowing details without attributes
+	policy := &Policy{Tags: map[string][]string{"details": nil, "summary": nil}}
+	assert.Equal(t, `Title <details class="spoiler"><summary>Spoiler</summary>secret</details> u s`,
+		GetSafeHTML(body, Options{Policy: policy, Entities: EntityOptions{Spoiler: SpoilerDetails}}))
+	assert.Nil(t, policy.Tags["details"])
+	assert.Equal(t, `<b>Title</b> [spoiler] <u>u</u> <s>s</s>`, GetSafeHTML(body, Options{}))
+	assert.Equal(t, `<b>Title</b> secret <u>u</u> <s>s</s>`, GetSafeHTML(body, Options{Entities: EntityOptions{Spoiler: SpoilerText}}))
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Errors of the channel page, returned wrapped with the details
+var (
+	// ErrChannelNotFound means there is no channel with the name
+	ErrChannelNotFound = errors.New("channel not found")
+	// ErrPreviewDisabled means the channel exists but has no web preview, e.g. it's private or preview is turned off
+	ErrPreviewDisabled = errors.New("channel web preview is disabled")
+	// ErrRateLimited means Telegram refused the request because of too many requests
+	ErrRateLimited = errors.New("rate limited by Telegram")
+	// ErrMarkupChanged means the page has none of the expected channel markup
+	ErrMarkupChanged = errors.New("unexpected page markup")
+)
+
+// webPreviewPrefix is the path prefix of the channel web preview
+const webPreviewPrefix = "/s/"
+
+// GetStatusError returns the error of the channel page response status, nil for 200 OK
+func GetStatusError(statusCode int) error {
+	switch statusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrChannelNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+	return fmt.Errorf("status code error: %d %s", statusCode, http.StatusText(statusCode))
+}
+
+// GetPageError returns the error of the page loaded from the URL, nil if it's the channel web preview.
+// Telegram redirects web preview of the channel without it to the channel info page,
+// which has the channel title for existing channels only.
+func GetPageError(doc *goquery.Document, pageURL *url.URL) error {
+	if pageURL != nil && !strings.HasPrefix(pageURL.Path, webPreviewPrefix) {
+		if strings.TrimSpace(doc.Find(".tgme_page_title").Text()) != "" {
+			return fmt.Errorf("%w: redirected to %s", ErrPreviewDisabled, pageURL)
+		}
+		return fmt.Errorf("%w: redirected to %s", ErrChannelNotFound, pageURL)
+	}
+	if doc.Find(".tgme_channel_info_header_title").Length() == 0 {
+		return fmt.Errorf("%w: channel header not found", ErrMarkupChanged)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"errors"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGetStatusError(t *testing.T) {
+	assert.Nil(t, GetStatusError(http.StatusOK))
+	assert.Equal(t, ErrChannelNotFound, GetStatusError(http.StatusNotFound))
+	assert.Equal(t, ErrRateLimited, GetStatusError(http.StatusTooManyRequests))
+	assert.EqualError(t, GetStatusError(http.StatusBadGateway), "status code error: 502 Bad Gateway")
+}
+
+func TestGetPageError(t *testing.T) {
+	previewURL, _ := url.Parse("https://t.me/s/telegram")
+	redirectURL, _ := url.Parse("https://t.me/telegram")
+	tbl := []struct {
+		html    string
+		pageURL *url.URL
+		err     error
+	}{
+		{testPageHTML, previewURL, nil},
+		{testPageHTML, nil, nil},
+		{`<div class="tgme_page"><div class="tgme_page_title"><span>Private</span></div></div>`, redirectURL, ErrPreviewDisabled},
+		{`<div class="tgme_page"><div class="tgme_page_title"> </div></div>`, redirectURL, ErrChannelNotFound},
+		{`<div class="tgme_page"></div>`, redirectURL, ErrChannelNotFound},
+		{`<div class="tgme_page_widget"></div>`, previewURL, ErrMarkupChanged},
+	}
+	for _, tb := range tbl {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tb.html))
+		assert.Nil(t, err)
+		err = GetPageError(doc, tb.pageURL)
+		if tb.err == nil {
+			assert.Nil(t, err)
+			continue
+		}
+		assert.True(t, errors.Is(err, tb.err), "expected %v, got %v", tb.err, err)
+	}
+}
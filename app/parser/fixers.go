@@ -1,6 +1,14 @@
 package parser
 
-import "github.com/PuerkitoBio/goquery"
+import (
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"regexp"
+)
+
+// Mention of the channel or user, e.g. "@telegram", not part of e-mail
+var mentionRe = regexp.MustCompile(`(^|[^\w@.])@([A-Za-z][A-Za-z0-9_]{3,31})\b`)
 
 // FixEmoji removes all emoji tags
 func FixEmoji(s *goquery.Selection) *goquery.Selection {
@@ -32,16 +40,78 @@ func FixLinks(s *goquery.Selection) *goquery.Selection {
 	return s
 }
 
-// RemoveUnsafeTags removes all tags except <a>, <i>, <b>, <br>
-func RemoveUnsafeTags(s *goquery.Selection) *goquery.Selection {
+// UnwrapLinks converts tg:// deep links to https://t.me links and unwraps redirect URLs to their targets
+func UnwrapLinks(s *goquery.Selection) *goquery.Selection {
 	// Clone the selection to avoid modifying the original
 	s = s.Clone()
 
-	// Remove all tags except <a>, <i>, <b>, <br>
-	s.Find("*").Each(func(_ int, s *goquery.Selection) {
-		if !s.Is("a, i, b, br") {
-			s.ReplaceWithHtml(s.Text())
-		}
+	s.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		s.SetAttr("href", UnwrapLink(href))
 	})
 	return s
 }
+
+// LinkMentions replaces plain text mentions like @telegram with links to the channel
+func LinkMentions(s *goquery.Selection) *goquery.Selection {
+	// Clone the selection to avoid modifying the original
+	s = s.Clone()
+
+	for _, node := range s.Nodes {
+		linkMentions(node)
+	}
+	return s
+}
+
+// linkMentions splits text nodes by mentions, text of links and code is kept as is
+func linkMentions(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			if c.DataAtom != atom.A && c.DataAtom != atom.Pre && c.DataAtom != atom.Code {
+				linkMentions(c)
+			}
+			continue
+		}
+		if c.Type != html.TextNode {
+			continue
+		}
+		text := c.Data
+		matches := mentionRe.FindAllStringSubmatchIndex(text, -1)
+		if matches == nil {
+			continue
+		}
+		last := 0
+		for _, m := range matches {
+			// m[4]:m[5] is the name, "@" goes right before it
+			start, end := m[4]-1, m[5]
+			if start > last {
+				n.InsertBefore(&html.Node{Type: html.TextNode, Data: text[last:start]}, c)
+			}
+			link := &html.Node{
+				Type:     html.ElementNode,
+				Data:     "a",
+				DataAtom: atom.A,
+				Attr:     []html.Attribute{{Key: "href", Val: "https://t.me/" + text[m[4]:m[5]]}},
+			}
+			link.AppendChild(&html.Node{Type: html.TextNode, Data: text[start:end]})
+			n.InsertBefore(link, c)
+			last = end
+		}
+		c.Data = text[last:]
+	}
+}
+
+// FixCodeBlocks replaces line breaks in code blocks with new lines to keep the code formatting
+func FixCodeBlocks(s *goquery.Selection) *goquery.Selection {
+	// Clone the selection to avoid modifying the original
+	s = s.Clone()
+
+	// replace <pre>line<br>line</pre> with <pre>line\nline</pre>
+	s.Find("pre br").ReplaceWithHtml("\n")
+	return s
+}
+
+// RemoveUnsafeTags removes all tags except <a>, <b>, <i>, <u>, <s>, <blockquote>, <br> using the strict policy
+func RemoveUnsafeTags(s *goquery.Selection) *goquery.Selection {
+	return Sanitize(s, StrictPolicy())
+}
@@ -49,6 +49,44 @@ func TestFixLinks(t *testing.T) {
 	assert.Equal(t, `<a href="https://t.me/s/telegram">telegram</a>`, fixedBodyHTML)
 }
 
+func TestUnwrapLinks(t *testing.T) {
+	const html = `<body><a href="tg://resolve?domain=telegram&amp;post=1">post</a> <a href="https://href.li/?https://example.com">site</a></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	body := doc.Find("body")
+	fixedBody := UnwrapLinks(body)
+	assert.NotEqualf(t, body, fixedBody, "body and fixedBody should not be equal")
+
+	fixedBodyHTML, err := fixedBody.Html()
+	assert.Nil(t, err)
+	assert.Equal(t, `<a href="https://t.me/telegram/1">post</a> <a href="https://example.com">site</a>`, fixedBodyHTML)
+}
+
+func TestLinkMentions(t *testing.T) {
+	tbl := []struct {
+		html string
+		out  string
+	}{
+		{`Hi @telegram!`, `Hi <a href="https://t.me/telegram">@telegram</a>!`},
+		{`@durov and <b>@telegram</b>`, `<a href="https://t.me/durov">@durov</a> and <b><a href="https://t.me/telegram">@telegram</a></b>`},
+		{`<a href="https://t.me/telegram">@telegram</a>`, `<a href="https://t.me/telegram">@telegram</a>`},
+		{`<code>@decorator</code> me@example.com @abc`, `<code>@decorator</code> me@example.com @abc`},
+	}
+	for _, tb := range tbl {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader("<body>" + tb.html + "</body>"))
+		assert.Nil(t, err)
+
+		body := doc.Find("body")
+		fixedBody := LinkMentions(body)
+		assert.NotEqualf(t, body, fixedBody, "body and fixedBody should not be equal")
+
+		fixedBodyHTML, err := fixedBody.Html()
+		assert.Nil(t, err)
+		assert.Equal(t, tb.out, fixedBodyHTML)
+	}
+}
+
 func TestRemoveUnsafeTags(t *testing.T) {
 	const html = `<body><i>t</i><img src="img.jpg"><b>e</b><a href="link">s<to-remove>t</to-remove></a><br><br/></body>`
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
@@ -65,3 +103,20 @@ func TestRemoveUnsafeTags(t *testing.T) {
 
 	assert.Equal(t, `<i>t</i><b>e</b><a href="link">st</a><br/><br/>`, fixedBodyHTML)
 }
+
+func TestFixCodeBlocks(t *testing.T) {
+	const html = `<body><pre>line 1<br/>  line 2<br>line 3</pre>text<br/>text</body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	body := doc.Find("body")
+
+	fixedBody := FixCodeBlocks(body)
+
+	assert.NotEqualf(t, body, fixedBody, "body and fixedBody should not be equal")
+
+	fixedBodyHTML, err := fixedBody.Html()
+	assert.Nil(t, err)
+
+	assert.Equal(t, "<pre>line 1\n  line 2\nline 3</pre>text<br/>text", fixedBodyHTML)
+}
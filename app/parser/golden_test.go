@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Run "go test ./app/parser ./app/feed -update" to regenerate golden files after intended changes
+var update = flag.Bool("update", false, "update golden files")
+
+// goldenPages are the saved channel web preview pages, shared with feed golden tests
+const goldenPages = "testdata/pages"
+
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(goldenPages, "*.html"))
+	assert.Nil(t, err)
+	recorded, err := filepath.Glob(filepath.Join(goldenPages, "*.http"))
+	assert.Nil(t, err)
+	files = append(files, recorded...)
+	assert.NotEmpty(t, files)
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		t.Run(name, func(t *testing.T) {
+			doc, err := readGoldenPage(file)
+			assert.Nil(t, err)
+
+			data, err := json.MarshalIndent(GetPage(doc, Options{}), "", "  ")
+			assert.Nil(t, err)
+			data = append(data, '\n')
+
+			goldenFile := filepath.Join("testdata", "golden", name+".json")
+			if *update {
+				assert.Nil(t, os.WriteFile(goldenFile, data, 0o600))
+			}
+			expected, err := os.ReadFile(goldenFile) //nolint:gosec // test data
+			assert.Nil(t, err)
+			assert.Equal(t, string(expected), string(data))
+		})
+	}
+}
+
+// readGoldenPage reads the page saved from the browser (.html) or recorded with --record-dir (.http)
+func readGoldenPage(file string) (*goquery.Document, error) {
+	fh, err := os.Open(file) //nolint:gosec // test data
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() // nolint
+	if filepath.Ext(file) != ".http" {
+		return goquery.NewDocumentFromReader(fh)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(fh), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close() // nolint
+	return goquery.NewDocumentFromReader(res.Body)
+}
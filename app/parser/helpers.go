@@ -3,34 +3,149 @@ package parser
 import (
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 	"strings"
 	"time"
 	"unicode"
 )
 
-// GetSafeHTML returns the HTML string without unsafe tags
-func GetSafeHTML(s *goquery.Selection) string {
-	// Fix emoji
+// GetSafeHTML returns the HTML string without tags and attributes not allowed by the policy
+func GetSafeHTML(s *goquery.Selection, opts Options) string {
+	// Render custom emoji as images and fix the rest of emoji
+	if opts.Entities.CustomEmoji {
+		s = FixCustomEmoji(s)
+	}
 	s = FixEmoji(s)
 
+	// Rewrite media URLs by the proxy template
+	s = ProxyMedia(s, opts.Media)
+
 	// Fix links
 	s = FixLinks(s)
 
-	// Remove unsafe tags
-	s = RemoveUnsafeTags(s)
+	// Unwrap deep links and redirects, link mentions and rewrite t.me links
+	s = UnwrapLinks(s)
+	s = LinkMentions(s)
+	s = RewriteLinks(s, opts.Links)
+
+	// Fix code blocks
+	s = FixCodeBlocks(s)
+
+	// Render spoilers and other entities
+	s = FixEntities(s, opts.Entities)
+
+	// Remove unsafe tags keeping the ones produced by entities rendering
+	policy := opts.Policy
+	if policy == nil {
+		policy = StrictPolicy()
+	}
+	policy = policy.withTags(opts.Entities.tags())
+	s = breakCodeLines(s, policy)
+
+	// Remove new lines and tabs outside of code, before code tags not allowed by the policy are removed
+	for _, node := range s.Nodes {
+		normalizeWhitespace(node)
+	}
+	s = Sanitize(s, policy)
 
 	html, err := s.Html()
 	if err != nil {
 		return ""
 	}
+	return strings.TrimSpace(html)
+}
 
-	// Remove new lines
-	html = strings.ReplaceAll(html, "\n", "")
+// breakCodeLines replaces new lines with <br/> in <pre> and <code> not allowed by the policy,
+// so line breaks of the code survive flattening of these tags to text
+func breakCodeLines(s *goquery.Selection, policy *Policy) *goquery.Selection {
+	_, preAllowed := policy.Tags["pre"]
+	_, codeAllowed := policy.Tags["code"]
+	if preAllowed && codeAllowed {
+		return s
+	}
+	// Clone the selection to avoid modifying the original
+	s = s.Clone()
 
-	// Replace \t with space
-	html = strings.ReplaceAll(html, "\t", " ")
+	s.Find("pre, code").Each(func(_ int, s *goquery.Selection) {
+		node := s.Get(0)
+		allowed := codeAllowed
+		if node.DataAtom == atom.Pre {
+			allowed = preAllowed
+		} else if s.ParentsFiltered("pre").Length() > 0 {
+			// Code block is handled with its <pre>
+			return
+		}
+		if !allowed {
+			breakTextLines(node)
+		}
+		if !preAllowed && node.DataAtom == atom.Pre {
+			breakBlock(node)
+		}
+	})
+	return s
+}
 
-	return strings.TrimSpace(html)
+// breakBlock separates the block from the adjacent content with <br/> elements unless they are already there
+func breakBlock(n *html.Node) {
+	if prev := getAdjacentNode(n, false); prev != nil && prev.DataAtom != atom.Br {
+		n.Parent.InsertBefore(&html.Node{Type: html.ElementNode, Data: "br", DataAtom: atom.Br}, n)
+	}
+	if next := getAdjacentNode(n, true); next != nil && next.DataAtom != atom.Br {
+		n.Parent.InsertBefore(&html.Node{Type: html.ElementNode, Data: "br", DataAtom: atom.Br}, n.NextSibling)
+	}
+}
+
+// getAdjacentNode returns the next or previous sibling skipping whitespace text, nil if there is none
+func getAdjacentNode(n *html.Node, next bool) *html.Node {
+	for {
+		if next {
+			n = n.NextSibling
+		} else {
+			n = n.PrevSibling
+		}
+		if n == nil || n.Type != html.TextNode || strings.TrimSpace(n.Data) != "" {
+			return n
+		}
+	}
+}
+
+// breakTextLines replaces new lines of the descendant text nodes with <br/> elements
+func breakTextLines(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch {
+		case c.Type == html.TextNode && strings.Contains(c.Data, "\n"):
+			for i, line := range strings.Split(c.Data, "\n") {
+				if i > 0 {
+					n.InsertBefore(&html.Node{Type: html.ElementNode, Data: "br", DataAtom: atom.Br}, c)
+				}
+				if line != "" {
+					n.InsertBefore(&html.Node{Type: html.TextNode, Data: line}, c)
+				}
+			}
+			n.RemoveChild(c)
+		case c.Type == html.ElementNode:
+			breakTextLines(c)
+		}
+		c = next
+	}
+}
+
+// normalizeWhitespace removes new lines and replaces tabs with spaces in the text nodes,
+// whitespace of <pre> and <code> content is preserved
+func normalizeWhitespace(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode:
+			c.Data = strings.ReplaceAll(c.Data, "\n", "")
+			c.Data = strings.ReplaceAll(c.Data, "\t", " ")
+		case c.Type == html.ElementNode && (c.DataAtom == atom.Pre || c.DataAtom == atom.Code):
+			// keep code as is
+		default:
+			normalizeWhitespace(c)
+		}
+	}
 }
 
 // ParseDateTime parses the datetime string and returns the time.Time object
@@ -20,17 +20,29 @@ func TestGetSafeHTML(t *testing.T) {
 
 	body := doc.Find("body")
 
-	safeHTML := GetSafeHTML(body)
+	safeHTML := GetSafeHTML(body, Options{})
 	assert.Equal(t, `<a href="https://t.me/s/telegram">telegram</a> 👍 👍`, safeHTML)
 
 	// Empty body
 	doc, err = goquery.NewDocumentFromReader(strings.NewReader(""))
 	assert.Nil(t, err)
 	body = doc.Find("body")
-	safeHTML = GetSafeHTML(body)
+	safeHTML = GetSafeHTML(body, Options{})
 	assert.Equal(t, "", safeHTML)
 }
 
+func TestGetSafeHTML_Links(t *testing.T) {
+	const html = `<body>Follow @telegram, <a href="tg://resolve?domain=durov&amp;post=1">post</a></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+	body := doc.Find("body")
+
+	assert.Equal(t, `Follow <a href="https://t.me/s/telegram">@telegram</a>, <a href="https://t.me/s/durov/1">post</a>`,
+		GetSafeHTML(body, Options{}))
+	assert.Equal(t, `Follow <a href="https://t.me/telegram">@telegram</a>, <a href="https://t.me/durov/1">post</a>`,
+		GetSafeHTML(body, Options{Links: LinkOptions{Mode: LinkNative}}))
+}
+
 func TestParseDateTime(t *testing.T) {
 	tbl := []struct {
 		inp string
@@ -68,3 +80,26 @@ func TestShortenText(t *testing.T) {
 		assert.Equal(t, tb.out, short)
 	}
 }
+
+func TestGetSafeHTML_Code(t *testing.T) {
+	const html = "<body>\n\tSome\tcode:\n<pre><code>func main() {<br/>\tfmt.Println(\"hello\")<br/>}</code></pre>\n<code>a\tb</code>\n</body>"
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	body := doc.Find("body")
+
+	safeHTML := GetSafeHTML(body, Options{Policy: FormattingPolicy()})
+	assert.Equal(t, "Some code:<pre><code>func main() {\n\tfmt.Println(&#34;hello&#34;)\n}</code></pre><code>a\tb</code>", safeHTML)
+
+	// Line breaks, tabs and block boundaries of code are kept when code tags are not allowed
+	safeHTML = GetSafeHTML(body, Options{})
+	assert.Equal(t, "Some code:<br/>func main() {<br/>\tfmt.Println(&#34;hello&#34;)<br/>}<br/>a\tb", safeHTML)
+
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader("<body><pre>a := 1\nb := 2</pre><code>x\ny</code></body>"))
+	assert.Nil(t, err)
+	assert.Equal(t, "a := 1<br/>b := 2<br/>x<br/>y", GetSafeHTML(doc.Find("body"), Options{}))
+	assert.Equal(t, "<pre>a := 1\nb := 2</pre><code>x\ny</code>",
+		GetSafeHTML(doc.Find("body"), Options{Policy: &Policy{Tags: map[string][]string{"br": nil, "pre": nil, "code": nil}}}))
+	assert.Equal(t, "a := 1<br/>b := 2<br/><code>x\ny</code>",
+		GetSafeHTML(doc.Find("body"), Options{Policy: &Policy{Tags: map[string][]string{"br": nil, "code": nil}}}))
+}
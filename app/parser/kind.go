@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"html"
+	"strings"
+)
+
+// PostKind is the kind of the channel message
+type PostKind string
+
+// Supported post kinds
+const (
+	KindRegular PostKind = "regular"
+	// KindService is the service message, e.g. channel photo or title changed, message pinned
+	KindService PostKind = "service"
+	KindPoll    PostKind = "poll"
+	KindSticker PostKind = "sticker"
+)
+
+// KindAction defines what to do with the posts of the kind
+type KindAction string
+
+// Supported kind actions
+const (
+	// KindInclude keeps the post as is
+	KindInclude KindAction = "include"
+	// KindSkip removes the post
+	KindSkip KindAction = "skip"
+	// KindDescribe replaces the post text with the short description, e.g. "Channel photo updated"
+	KindDescribe KindAction = "describe"
+)
+
+// Default actions of the kinds not set in options, other kinds are included
+var defaultKindActions = map[PostKind]KindAction{
+	KindService: KindDescribe,
+	KindPoll:    KindDescribe,
+}
+
+// Telegram service message markup
+const serviceSelector = ".tgme_widget_message.service_message"
+
+// ParseKindActions parses comma separated list of kind actions, e.g. "service=skip, poll=describe"
+func ParseKindActions(str string) (map[PostKind]KindAction, error) {
+	actions := make(map[PostKind]KindAction)
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, found := strings.Cut(item, "=")
+		if !found {
+			return nil, fmt.Errorf("can't parse post kind action: %s", item)
+		}
+		kind := PostKind(strings.TrimSpace(name))
+		switch kind {
+		case KindRegular, KindService, KindPoll, KindSticker:
+		default:
+			return nil, fmt.Errorf("unknown post kind: %s", kind)
+		}
+		action := KindAction(strings.TrimSpace(value))
+		switch action {
+		case KindInclude, KindSkip, KindDescribe:
+		default:
+			return nil, fmt.Errorf("unknown post kind action: %s", action)
+		}
+		actions[kind] = action
+	}
+	return actions, nil
+}
+
+// getKindAction returns the action of the kind from the options or the default one
+func getKindAction(kind PostKind, actions map[PostKind]KindAction) KindAction {
+	if action, ok := actions[kind]; ok {
+		return action
+	}
+	if action, ok := defaultKindActions[kind]; ok {
+		return action
+	}
+	return KindInclude
+}
+
+// GetPostKind returns the kind of the message
+func GetPostKind(s *goquery.Selection) PostKind {
+	switch {
+	case s.Find(serviceSelector).Length() > 0:
+		return KindService
+	case s.Find(".tgme_widget_message_poll").Length() > 0:
+		return KindPoll
+	case GetSticker(s) != nil:
+		return KindSticker
+	}
+	return KindRegular
+}
+
+// GetPostDescription returns the short description of the message, e.g. "Poll: Question?"
+// as title and HTML text, empty strings for regular messages
+func GetPostDescription(s *goquery.Selection, kind PostKind) (string, string) {
+	switch kind {
+	case KindService:
+		text := getCleanText(s.Find(".tgme_widget_service_message_text, .tgme_widget_message_service_text, .tgme_widget_message_text").First())
+		if text == "" {
+			text = "Service message"
+		}
+		return text, "<p>" + html.EscapeString(text) + "</p>"
+	case KindPoll:
+		question := getCleanText(s.Find(".tgme_widget_message_poll_question").First())
+		lines := []string{"Poll: <b>" + html.EscapeString(question) + "</b>"}
+		s.Find(".tgme_widget_message_poll_option").Each(func(_ int, s *goquery.Selection) {
+			line := html.EscapeString(getCleanText(s.Find(".tgme_widget_message_poll_option_text")))
+			if percent := getCleanText(s.Find(".tgme_widget_message_poll_option_percent")); percent != "" {
+				line += " (" + html.EscapeString(percent) + ")"
+			}
+			lines = append(lines, line)
+		})
+		return strings.TrimSpace("Poll: " + question), "<p>" + strings.Join(lines, "<br/>") + "</p>"
+	case KindSticker:
+		return "Sticker", ""
+	}
+	return "", ""
+}
+
+// GetDocumentDescription returns the title and HTML text of the document or audio file attached to the message,
+// empty strings if there is none
+func GetDocumentDescription(s *goquery.Selection) (string, string) {
+	if s.Find(".tgme_widget_message_document").Length() == 0 {
+		return "", ""
+	}
+	// Document title is the file name or the audio title, extra is the file size or the audio performer
+	title := getCleanText(s.Find(".tgme_widget_message_document_title").First())
+	if title == "" {
+		title = "Document"
+	}
+	lines := []string{"<b>" + html.EscapeString(title) + "</b>"}
+	if extra := getCleanText(s.Find(".tgme_widget_message_document_extra").First()); extra != "" {
+		lines = append(lines, html.EscapeString(extra))
+	}
+	return title, "<p>" + strings.Join(lines, "<br/>") + "</p>"
+}
+
+// getCleanText returns the element text with collapsed whitespace
+func getCleanText(s *goquery.Selection) string {
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
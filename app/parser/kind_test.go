@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseKindActions(t *testing.T) {
+	tbl := []struct {
+		inp string
+		out map[PostKind]KindAction
+		err error
+	}{
+		{"", map[PostKind]KindAction{}, nil},
+		{"service=skip, poll = include,", map[PostKind]KindAction{KindService: KindSkip, KindPoll: KindInclude}, nil},
+		{"service", nil, fmt.Errorf("can't parse post kind action: service")},
+		{"video=skip", nil, fmt.Errorf("unknown post kind: video")},
+		{"poll=hide", nil, fmt.Errorf("unknown post kind action: hide")},
+	}
+	for _, tb := range tbl {
+		actions, err := ParseKindActions(tb.inp)
+		assert.Equal(t, tb.err, err)
+		assert.Equal(t, tb.out, actions)
+	}
+}
+
+func TestGetKindAction(t *testing.T) {
+	actions := map[PostKind]KindAction{KindPoll: KindSkip}
+	assert.Equal(t, KindSkip, getKindAction(KindPoll, actions))
+	assert.Equal(t, KindDescribe, getKindAction(KindService, actions))
+	assert.Equal(t, KindInclude, getKindAction(KindSticker, actions))
+	assert.Equal(t, KindInclude, getKindAction(KindRegular, nil))
+}
+
+const testServiceHTML = `<div class="tgme_widget_message_wrap"><div class="tgme_widget_message service_message" data-post="telegram/2">
+	<div class="tgme_widget_message_text">Channel name was changed to «News &amp; Updates»</div>
+</div></div>`
+
+const testPollHTML = `<div class="tgme_widget_message_wrap"><div class="tgme_widget_message" data-post="telegram/3">
+	<div class="tgme_widget_message_poll">
+		<div class="tgme_widget_message_poll_question">Tabs or   spaces?</div>
+		<div class="tgme_widget_message_poll_options">
+			<div class="tgme_widget_message_poll_option"><div class="tgme_widget_message_poll_option_percent">60%</div>
+				<div class="tgme_widget_message_poll_option_text">Tabs</div></div>
+			<div class="tgme_widget_message_poll_option"><div class="tgme_widget_message_poll_option_percent">40%</div>
+				<div class="tgme_widget_message_poll_option_text">Spaces</div></div>
+		</div>
+	</div>
+</div></div>`
+
+const testDocumentHTML = `<div class="tgme_widget_message_wrap"><div class="tgme_widget_message" data-post="telegram/4">
+	<a class="tgme_widget_message_document_wrap" href="https://t.me/telegram/4"><div class="tgme_widget_message_document">
+		<div class="tgme_widget_message_document_title">Podcast &amp; news</div>
+		<div class="tgme_widget_message_document_extra">Telegram</div>
+	</div></a>
+</div></div>`
+
+func TestGetDocumentDescription(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testDocumentHTML))
+	assert.Nil(t, err)
+	title, text := GetDocumentDescription(doc.Find("body"))
+	assert.Equal(t, "Podcast & news", title)
+	assert.Equal(t, "<p><b>Podcast &amp; news</b><br/>Telegram</p>", text)
+
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(testPollHTML))
+	assert.Nil(t, err)
+	title, text = GetDocumentDescription(doc.Find("body"))
+	assert.Equal(t, "", title)
+	assert.Equal(t, "", text)
+}
+
+func TestGetPostKind(t *testing.T) {
+	tbl := []struct {
+		html string
+		out  PostKind
+	}{
+		{testPostHTML, KindRegular},
+		{testServiceHTML, KindService},
+		{testPollHTML, KindPoll},
+		{`<i class="tgme_widget_message_sticker" data-webp="https://cdn.example.com/sticker.webp"></i>`, KindSticker},
+		{testDocumentHTML, KindRegular},
+	}
+	for _, tb := range tbl {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tb.html))
+		assert.Nil(t, err)
+		assert.Equal(t, tb.out, GetPostKind(doc.Find("body")))
+	}
+}
+
+func TestGetPostDescription(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testServiceHTML))
+	assert.Nil(t, err)
+	title, text := GetPostDescription(doc.Find("body"), KindService)
+	assert.Equal(t, "Channel name was changed to «News & Updates»", title)
+	assert.Equal(t, "<p>Channel name was changed to «News &amp; Updates»</p>", text)
+
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(testPollHTML))
+	assert.Nil(t, err)
+	title, text = GetPostDescription(doc.Find("body"), KindPoll)
+	assert.Equal(t, "Poll: Tabs or spaces?", title)
+	assert.Equal(t, "<p>Poll: <b>Tabs or spaces?</b><br/>Tabs (60%)<br/>Spaces (40%)</p>", text)
+
+	title, text = GetPostDescription(doc.Find("body"), KindRegular)
+	assert.Equal(t, "", title)
+	assert.Equal(t, "", text)
+}
+
+func TestGetPosts_Kinds(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader("<body>" + testServiceHTML + testPollHTML + "</body>"))
+	assert.Nil(t, err)
+
+	posts := GetPosts(doc, Options{})
+	assert.Equal(t, 2, len(posts))
+	assert.Equal(t, KindService, posts[0].Kind)
+	assert.Equal(t, "Channel name was changed to...", posts[0].Title)
+	assert.Equal(t, "Channel name was changed to «News & Updates»", posts[0].PlainText)
+	assert.Equal(t, KindPoll, posts[1].Kind)
+	assert.Equal(t, "Poll: Tabs or spaces?", posts[1].Title)
+
+	posts = GetPosts(doc, Options{Kinds: map[PostKind]KindAction{KindService: KindSkip, KindPoll: KindInclude}})
+	assert.Equal(t, 1, len(posts))
+	assert.Equal(t, KindPoll, posts[0].Kind)
+	assert.Equal(t, "", posts[0].Text)
+
+	// Regular post of the document without caption is described by the document
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader("<body>" + testDocumentHTML + "</body>"))
+	assert.Nil(t, err)
+	posts = GetPosts(doc, Options{})
+	assert.Equal(t, 1, len(posts))
+	assert.Equal(t, KindRegular, posts[0].Kind)
+	assert.Equal(t, "Podcast & news", posts[0].Title)
+	assert.Equal(t, "<p><b>Podcast &amp; news</b><br/>Telegram</p>", posts[0].Text)
+}
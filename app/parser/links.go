@@ -0,0 +1,236 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// LinkMode defines how t.me links in the post text are rewritten
+type LinkMode string
+
+// Supported link modes
+const (
+	// LinkNative keeps t.me links as is
+	LinkNative LinkMode = "native"
+	// LinkPreview rewrites channel and post links to the web preview, e.g. https://t.me/s/channel/1
+	LinkPreview LinkMode = "preview"
+	// LinkMirror rewrites channel and post links to the mirror base URL
+	LinkMirror LinkMode = "mirror"
+)
+
+// LinkOptions defines how links in the post text are rewritten
+type LinkOptions struct {
+	// Mode of t.me links rewriting, preview if not set
+	Mode LinkMode
+	// MirrorURL is the base URL for the mirror mode, e.g. https://mirror.example.com
+	MirrorURL string
+	// StripTracking removes tracking query params like utm_source from external links
+	StripTracking bool
+}
+
+var telegramHosts = map[string]bool{"t.me": true, "telegram.me": true, "www.t.me": true, "www.telegram.me": true}
+
+// Channel or post link path, e.g. /channel or /channel/123
+var channelPathRe = regexp.MustCompile(`^/([A-Za-z][A-Za-z0-9_]{3,31})(/\d+)?/?$`)
+
+// Reserved paths of t.me which are not channels
+var reservedPaths = map[string]bool{
+	"joinchat": true, "addstickers": true, "addemoji": true, "addlist": true, "addtheme": true, "proxy": true,
+	"socks": true, "share": true, "setlanguage": true, "login": true, "invoice": true, "boost": true,
+	"contact": true, "iv": true, "bg": true, "confirmphone": true, "giftcode": true,
+}
+
+// Bot deep link params
+var botParams = []string{"start", "startgroup", "startchannel", "startapp", "game", "admin"}
+
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "yclid": true, "dclid": true, "msclkid": true, "igshid": true,
+	"mc_cid": true, "mc_eid": true, "_openstat": true,
+}
+
+// ParseLinkMode parses the link mode name
+func ParseLinkMode(str string) (LinkMode, error) {
+	mode := LinkMode(strings.TrimSpace(str))
+	switch mode {
+	case "":
+		return LinkPreview, nil
+	case LinkNative, LinkPreview, LinkMirror:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown link mode: %s", str)
+}
+
+// RewriteLinks rewrites href of all links
+func RewriteLinks(s *goquery.Selection, opts LinkOptions) *goquery.Selection {
+	// Clone the selection to avoid modifying the original
+	s = s.Clone()
+
+	s.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		s.SetAttr("href", RewriteLink(href, opts))
+	})
+	return s
+}
+
+// RewriteLink returns the link rewritten according to the options
+func RewriteLink(link string, opts LinkOptions) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	if !telegramHosts[strings.ToLower(u.Host)] {
+		if opts.StripTracking {
+			return stripTrackingParams(u, link)
+		}
+		return link
+	}
+
+	channelPath := getChannelPath(u)
+	if channelPath == "" {
+		return link
+	}
+	switch opts.Mode {
+	case LinkNative:
+		return link
+	case LinkMirror:
+		if opts.MirrorURL == "" {
+			return link
+		}
+		return strings.TrimRight(opts.MirrorURL, "/") + channelPath
+	default:
+		return "https://t.me/s" + channelPath
+	}
+}
+
+// getChannelPath returns the path of channel or post link, e.g. /channel/1, or empty string for other t.me links
+func getChannelPath(u *url.URL) string {
+	// Already web preview link
+	path := strings.TrimPrefix(u.Path, "/s/")
+	if path != u.Path {
+		path = "/" + path
+	}
+	m := channelPathRe.FindStringSubmatch(path)
+	if m == nil {
+		return ""
+	}
+	name := strings.ToLower(m[1])
+	if reservedPaths[name] || strings.HasSuffix(name, "bot") {
+		return ""
+	}
+	query := u.Query()
+	for _, param := range botParams {
+		if query.Has(param) {
+			return ""
+		}
+	}
+	return strings.TrimRight(path, "/")
+}
+
+// stripTrackingParams removes tracking params from the URL query, link is returned as is if there are none
+func stripTrackingParams(u *url.URL, link string) string {
+	query := u.Query()
+	stripped := false
+	for param := range query {
+		if strings.HasPrefix(strings.ToLower(param), "utm_") || trackingParams[strings.ToLower(param)] {
+			query.Del(param)
+			stripped = true
+		}
+	}
+	if !stripped {
+		return link
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Known redirectors mapped to the query param with the target URL
+var redirectParams = map[string]string{
+	"t.me/iv":                  "url",
+	"l.facebook.com/l.php":     "u",
+	"lm.facebook.com/l.php":    "u",
+	"www.google.com/url":       "q",
+	"google.com/url":           "q",
+	"vk.com/away.php":          "to",
+	"m.vk.com/away.php":        "to",
+	"href.li/":                 "",
+	"away.vk.com/away.php":     "to",
+	"www.youtube.com/redirect": "q",
+}
+
+// Max nesting of redirect URLs
+const maxRedirectDepth = 3
+
+// UnwrapLink converts tg:// deep link to https://t.me link and unwraps redirect URL to its target.
+// Link is returned as is if it's neither.
+func UnwrapLink(link string) string {
+	for i := 0; i < maxRedirectDepth; i++ {
+		u, err := url.Parse(strings.TrimSpace(link))
+		if err != nil {
+			return link
+		}
+		if strings.EqualFold(u.Scheme, "tg") {
+			return resolveDeepLink(u, link)
+		}
+		target := getRedirectTarget(u)
+		if target == "" {
+			return link
+		}
+		link = target
+	}
+	return link
+}
+
+// resolveDeepLink returns https://t.me link for tg:// deep link, link is returned as is if it can't be converted
+func resolveDeepLink(u *url.URL, link string) string {
+	query := u.Query()
+	switch strings.ToLower(u.Host) {
+	case "resolve":
+		domain := query.Get("domain")
+		if domain == "" || strings.ContainsAny(domain, "/?#") {
+			return link
+		}
+		path := "/" + domain
+		if post := query.Get("post"); post != "" {
+			path += "/" + url.PathEscape(post)
+		}
+		tmeURL := &url.URL{Scheme: "https", Host: "t.me", Path: path}
+		if start := query.Get("start"); start != "" {
+			tmeURL.RawQuery = url.Values{"start": {start}}.Encode()
+		}
+		return tmeURL.String()
+	case "join":
+		if invite := query.Get("invite"); invite != "" {
+			return "https://t.me/+" + url.PathEscape(invite)
+		}
+	case "addstickers":
+		if set := query.Get("set"); set != "" {
+			return "https://t.me/addstickers/" + url.PathEscape(set)
+		}
+	case "privatepost":
+		channel, post := query.Get("channel"), query.Get("post")
+		if channel != "" && post != "" {
+			return "https://t.me/c/" + url.PathEscape(channel) + "/" + url.PathEscape(post)
+		}
+	}
+	return link
+}
+
+// getRedirectTarget returns the target http(s) URL of the known redirector or empty string
+func getRedirectTarget(u *url.URL) string {
+	param, ok := redirectParams[strings.ToLower(u.Host)+u.Path]
+	if !ok {
+		return ""
+	}
+	target := u.RawQuery
+	if param != "" {
+		target = u.Query().Get(param)
+	}
+	targetURL, err := url.Parse(target)
+	if err != nil || targetURL.Host == "" || (targetURL.Scheme != "http" && targetURL.Scheme != "https") {
+		return ""
+	}
+	return target
+}
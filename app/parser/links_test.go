@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseLinkMode(t *testing.T) {
+	tbl := []struct {
+		inp string
+		out LinkMode
+		err error
+	}{
+		{"", LinkPreview, nil},
+		{"native", LinkNative, nil},
+		{" preview ", LinkPreview, nil},
+		{"mirror", LinkMirror, nil},
+		{"unknown", "", fmt.Errorf("unknown link mode: unknown")},
+	}
+	for _, tb := range tbl {
+		mode, err := ParseLinkMode(tb.inp)
+		assert.Equal(t, tb.err, err)
+		assert.Equal(t, tb.out, mode)
+	}
+}
+
+func TestRewriteLink(t *testing.T) {
+	mirror := LinkOptions{Mode: LinkMirror, MirrorURL: "https://mirror.example.com/"}
+	tbl := []struct {
+		link string
+		opts LinkOptions
+		out  string
+	}{
+		// Channel and post links
+		{"https://t.me/telegram", LinkOptions{}, "https://t.me/s/telegram"},
+		{"https://t.me/telegram/123", LinkOptions{}, "https://t.me/s/telegram/123"},
+		{"https://telegram.me/telegram/123/", LinkOptions{}, "https://t.me/s/telegram/123"},
+		{"https://t.me/s/telegram/123", LinkOptions{}, "https://t.me/s/telegram/123"},
+		{"https://t.me/telegram/123", LinkOptions{Mode: LinkNative}, "https://t.me/telegram/123"},
+		{"https://t.me/telegram/123", mirror, "https://mirror.example.com/telegram/123"},
+		{"https://t.me/s/telegram", mirror, "https://mirror.example.com/telegram"},
+		{"https://t.me/telegram/123", LinkOptions{Mode: LinkMirror}, "https://t.me/telegram/123"},
+
+		// Other t.me links are kept as is
+		{"https://t.me/joinchat/AAAAAE", LinkOptions{}, "https://t.me/joinchat/AAAAAE"},
+		{"https://t.me/+AbCdEf123", LinkOptions{}, "https://t.me/+AbCdEf123"},
+		{"https://t.me/addstickers/Animals", LinkOptions{}, "https://t.me/addstickers/Animals"},
+		{"https://t.me/proxy?server=x&port=1", LinkOptions{}, "https://t.me/proxy?server=x&port=1"},
+		{"https://t.me/somebot", LinkOptions{}, "https://t.me/somebot"},
+		{"https://t.me/channel?start=ref", LinkOptions{}, "https://t.me/channel?start=ref"},
+		{"https://t.me/addstickers/Animals", mirror, "https://t.me/addstickers/Animals"},
+
+		// External links
+		{"https://example.com/?utm_source=tg&id=1", LinkOptions{}, "https://example.com/?utm_source=tg&id=1"},
+		{"https://example.com/?utm_source=tg&id=1&fbclid=x", LinkOptions{StripTracking: true}, "https://example.com/?id=1"},
+		{"https://example.com/?utm_source=tg", LinkOptions{StripTracking: true}, "https://example.com/"},
+		{"https://example.com/?b=2&a=1", LinkOptions{StripTracking: true}, "https://example.com/?b=2&a=1"},
+		{"mailto:me@example.com", LinkOptions{StripTracking: true}, "mailto:me@example.com"},
+		{"/relative", LinkOptions{}, "/relative"},
+	}
+	for _, tb := range tbl {
+		assert.Equal(t, tb.out, RewriteLink(tb.link, tb.opts), tb.link)
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	const html = `<body><a href="https://t.me/telegram/1">https://t.me/telegram/1</a> <a href="https://t.me/joinchat/AAA">join</a> <a>no href</a></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	body := doc.Find("body")
+	rewrittenBody := RewriteLinks(body, LinkOptions{})
+	assert.NotEqualf(t, body, rewrittenBody, "body and rewrittenBody should not be equal")
+
+	rewrittenHTML, err := rewrittenBody.Html()
+	assert.Nil(t, err)
+	// Link text is kept as is
+	assert.Equal(t, `<a href="https://t.me/s/telegram/1">https://t.me/telegram/1</a> <a href="https://t.me/joinchat/AAA">join</a> <a>no href</a>`, rewrittenHTML)
+}
+
+func TestUnwrapLink(t *testing.T) {
+	tbl := []struct {
+		link string
+		out  string
+	}{
+		{"tg://resolve?domain=telegram", "https://t.me/telegram"},
+		{"tg://resolve?domain=telegram&post=123", "https://t.me/telegram/123"},
+		{"tg://resolve?domain=somebot&start=ref", "https://t.me/somebot?start=ref"},
+		{"tg://resolve?domain=../x", "tg://resolve?domain=../x"},
+		{"tg://join?invite=AbCdEf", "https://t.me/+AbCdEf"},
+		{"tg://addstickers?set=Animals", "https://t.me/addstickers/Animals"},
+		{"tg://privatepost?channel=123&post=4", "https://t.me/c/123/4"},
+		{"tg://settings", "tg://settings"},
+		{"https://t.me/iv?url=https%3A%2F%2Fexample.com%2Fa&rhash=1", "https://example.com/a"},
+		{"https://l.facebook.com/l.php?u=https%3A%2F%2Fexample.com%2F%3Fa%3D1&h=x", "https://example.com/?a=1"},
+		{"https://www.google.com/url?q=https%3A%2F%2Fl.facebook.com%2Fl.php%3Fu%3Dhttps%253A%252F%252Fexample.com", "https://example.com"},
+		{"https://href.li/?https://example.com/a", "https://example.com/a"},
+		{"https://vk.com/away.php?to=javascript%3Aalert(1)", "https://vk.com/away.php?to=javascript%3Aalert(1)"},
+		{"https://example.com/url?q=https://other.com", "https://example.com/url?q=https://other.com"},
+		{"https://t.me/telegram", "https://t.me/telegram"},
+	}
+	for _, tb := range tbl {
+		assert.Equal(t, tb.out, UnwrapLink(tb.link), tb.link)
+	}
+}
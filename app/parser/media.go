@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/url"
+	"strings"
+)
+
+// Placeholders of the media proxy template
+const (
+	// proxyURLPlaceholder is replaced with query escaped media URL
+	proxyURLPlaceholder = "{url}"
+	// proxyURLBase64Placeholder is replaced with URL-safe base64 of media URL without padding
+	proxyURLBase64Placeholder = "{url_b64}"
+)
+
+// MediaOptions defines how media URLs are rewritten
+type MediaOptions struct {
+	// ProxyTemplate maps image, video and avatar URLs to the proxy, e.g. https://proxy.example/{url_b64}.
+	// Media URLs are kept as is if not set.
+	ProxyTemplate string
+}
+
+// ParseProxyTemplate checks the media proxy template has URL placeholder
+func ParseProxyTemplate(str string) (string, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return "", nil
+	}
+	if !strings.Contains(str, proxyURLPlaceholder) && !strings.Contains(str, proxyURLBase64Placeholder) {
+		return "", fmt.Errorf("media proxy template has no %s or %s placeholder: %s", proxyURLPlaceholder, proxyURLBase64Placeholder, str)
+	}
+	return str, nil
+}
+
+// ProxyURL returns the media URL rewritten by the proxy template.
+// Empty and not http(s) URLs are returned as is.
+func (o MediaOptions) ProxyURL(mediaURL string) string {
+	if o.ProxyTemplate == "" || mediaURL == "" {
+		return mediaURL
+	}
+	if strings.HasPrefix(mediaURL, "//") {
+		mediaURL = "https:" + mediaURL
+	}
+	u, err := url.Parse(mediaURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return mediaURL
+	}
+	r := strings.NewReplacer(
+		proxyURLPlaceholder, url.QueryEscape(mediaURL),
+		proxyURLBase64Placeholder, base64.RawURLEncoding.EncodeToString([]byte(mediaURL)),
+	)
+	return r.Replace(o.ProxyTemplate)
+}
+
+// proxyURLs returns the media URLs rewritten by the proxy template
+func (o MediaOptions) proxyURLs(mediaURLs []string) []string {
+	if o.ProxyTemplate == "" {
+		return mediaURLs
+	}
+	for i, mediaURL := range mediaURLs {
+		mediaURLs[i] = o.ProxyURL(mediaURL)
+	}
+	return mediaURLs
+}
+
+// ProxyMedia rewrites src of images and videos by the proxy template
+func ProxyMedia(s *goquery.Selection, opts MediaOptions) *goquery.Selection {
+	// Clone the selection to avoid modifying the original
+	s = s.Clone()
+
+	if opts.ProxyTemplate == "" {
+		return s
+	}
+	s.Find("img[src], video[src]").Each(func(_ int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		s.SetAttr("src", opts.ProxyURL(src))
+	})
+	return s
+}
+
+// proxySticker returns the sticker with URLs rewritten by the proxy template
+func (o MediaOptions) proxySticker(sticker *Sticker) *Sticker {
+	if sticker == nil {
+		return nil
+	}
+	sticker.URL = o.ProxyURL(sticker.URL)
+	sticker.PreviewURL = o.ProxyURL(sticker.PreviewURL)
+	return sticker
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseProxyTemplate(t *testing.T) {
+	tbl := []struct {
+		inp string
+		out string
+		err error
+	}{
+		{"", "", nil},
+		{" https://proxy.example/{url_b64} ", "https://proxy.example/{url_b64}", nil},
+		{"https://proxy.example/?u={url}", "https://proxy.example/?u={url}", nil},
+		{"https://proxy.example/", "", fmt.Errorf("media proxy template has no {url} or {url_b64} placeholder: https://proxy.example/")},
+	}
+	for _, tb := range tbl {
+		tmpl, err := ParseProxyTemplate(tb.inp)
+		assert.Equal(t, tb.err, err)
+		assert.Equal(t, tb.out, tmpl)
+	}
+}
+
+func TestMediaOptions_ProxyURL(t *testing.T) {
+	const imageURL = "https://cdn4.cdn-telegram.org/file/a.jpg?x=1"
+	tbl := []struct {
+		opts MediaOptions
+		inp  string
+		out  string
+	}{
+		{MediaOptions{}, imageURL, imageURL},
+		{MediaOptions{ProxyTemplate: "https://proxy.example/{url_b64}"}, imageURL,
+			"https://proxy.example/aHR0cHM6Ly9jZG40LmNkbi10ZWxlZ3JhbS5vcmcvZmlsZS9hLmpwZz94PTE"},
+		{MediaOptions{ProxyTemplate: "https://proxy.example/?u={url}"}, imageURL,
+			"https://proxy.example/?u=https%3A%2F%2Fcdn4.cdn-telegram.org%2Ffile%2Fa.jpg%3Fx%3D1"},
+		{MediaOptions{ProxyTemplate: "https://proxy.example/?u={url}"}, "//telegram.org/img/a.png",
+			"https://proxy.example/?u=https%3A%2F%2Ftelegram.org%2Fimg%2Fa.png"},
+		{MediaOptions{ProxyTemplate: "https://proxy.example/?u={url}"}, "", ""},
+		{MediaOptions{ProxyTemplate: "https://proxy.example/?u={url}"}, "data:image/png;base64,AA", "data:image/png;base64,AA"},
+	}
+	for _, tb := range tbl {
+		assert.Equal(t, tb.out, tb.opts.ProxyURL(tb.inp), tb.inp)
+	}
+}
+
+func TestProxyMedia(t *testing.T) {
+	const html = `<body><img src="https://cdn.example.com/a.webp" alt="a"/><a href="https://example.com">link</a></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	body := doc.Find("body")
+	fixedBody := ProxyMedia(body, MediaOptions{ProxyTemplate: "https://proxy.example/?u={url}"})
+	assert.NotEqualf(t, body, fixedBody, "body and fixedBody should not be equal")
+
+	fixedBodyHTML, err := fixedBody.Html()
+	assert.Nil(t, err)
+	assert.Equal(t, `<img src="https://proxy.example/?u=https%3A%2F%2Fcdn.example.com%2Fa.webp" alt="a"/><a href="https://example.com">link</a>`, fixedBodyHTML)
+}
+
+func TestGetPage_MediaProxy(t *testing.T) {
+	opts := Options{Media: MediaOptions{ProxyTemplate: "https://proxy.example/?u={url}"}}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testPageHTML))
+	assert.Nil(t, err)
+	page := GetPage(doc, opts)
+	assert.Equal(t, "https://proxy.example/?u=https%3A%2F%2Fcdn4.cdn-telegram.org%2Ffile%2Fimg.jpg", page.ImageURL)
+
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(testPostHTML))
+	assert.Nil(t, err)
+	posts := GetPosts(doc, opts)
+	assert.Equal(t, 1, len(posts))
+	assert.NotEmpty(t, posts[0].Images)
+	for _, mediaURL := range append(posts[0].Images, posts[0].Videos...) {
+		assert.True(t, strings.HasPrefix(mediaURL, "https://proxy.example/?u="), mediaURL)
+	}
+}
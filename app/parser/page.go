@@ -1,6 +1,14 @@
 package parser
 
-import "github.com/PuerkitoBio/goquery"
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 // Page represents a page from the telegram channel
 type Page struct {
@@ -8,9 +16,28 @@ type Page struct {
 	Link        string
 	Description string
 	ImageURL    string
-	Posts       []*Post
+	// Counters are the channel counters from the page header
+	Counters Counters
+	// Verified is true for channels with the verified badge
+	Verified bool
+	Posts    []*Post
 }
 
+// Counters are the channel counters, zero if not shown
+type Counters struct {
+	Subscribers int
+	Photos      int
+	Videos      int
+	Links       int
+	Files       int
+}
+
+// Counter multipliers of the shortened values like "12.3K"
+var counterMultipliers = map[string]float64{"K": 1e3, "M": 1e6, "B": 1e9}
+
+// Counter value without separators and multiplier suffix, exponent and special values are not allowed
+var counterNumberRe = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
 // GetPageTitle returns the page title
 func GetPageTitle(doc *goquery.Document) string {
 	return doc.Find(".tgme_channel_info_header_title").Text()
@@ -25,8 +52,8 @@ func GetPageLink(doc *goquery.Document) string {
 }
 
 // GetPageDescriptionHTML returns the page description html
-func GetPageDescriptionHTML(doc *goquery.Document) string {
-	return GetSafeHTML(doc.Find(".tgme_channel_info_description"))
+func GetPageDescriptionHTML(doc *goquery.Document, opts Options) string {
+	return GetSafeHTML(doc.Find(".tgme_channel_info_description"), opts)
 }
 
 // GetPageImageURL returns the page image url
@@ -37,13 +64,66 @@ func GetPageImageURL(doc *goquery.Document) string {
 	return ""
 }
 
+// GetPageCounters returns the channel counters: subscribers, photos, videos, links and files
+func GetPageCounters(doc *goquery.Document) Counters {
+	var counters Counters
+	doc.Find(".tgme_channel_info_counter").Each(func(_ int, s *goquery.Selection) {
+		counterType := strings.TrimSpace(s.Find(".counter_type").Text())
+		value, err := ParseCounter(s.Find(".counter_value").Text())
+		if err != nil {
+			log.Printf("[ERROR] %v, skipping %s counter", err, counterType)
+			return
+		}
+		switch strings.TrimSuffix(strings.ToLower(counterType), "s") {
+		case "subscriber":
+			counters.Subscribers = value
+		case "photo":
+			counters.Photos = value
+		case "video":
+			counters.Videos = value
+		case "link":
+			counters.Links = value
+		case "file":
+			counters.Files = value
+		}
+	})
+	return counters
+}
+
+// ParseCounter parses the counter value like "987", "1 234" or "12.3K"
+func ParseCounter(str string) (int, error) {
+	value := strings.ToUpper(strings.Join(strings.Fields(str), ""))
+	multiplier := 1.0
+	if m, ok := counterMultipliers[value[max(len(value)-1, 0):]]; ok {
+		multiplier = m
+		value = value[:len(value)-1]
+	}
+	value = strings.ReplaceAll(value, ",", "")
+	if !counterNumberRe.MatchString(value) {
+		return 0, fmt.Errorf("can't parse counter: %q", str)
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	// Values not fitting int are rejected, float64(math.MaxInt) is rounded up to 2^63
+	if err != nil || math.Round(number*multiplier) >= float64(math.MaxInt) {
+		return 0, fmt.Errorf("can't parse counter: %q", str)
+	}
+	return int(math.Round(number * multiplier)), nil
+}
+
+// GetPageVerified reports whether the channel has the verified badge
+func GetPageVerified(doc *goquery.Document) bool {
+	return doc.Find(".tgme_channel_info_header_labels .verified-icon").Length() > 0
+}
+
 // GetPage returns the page object
-func GetPage(doc *goquery.Document) *Page {
+func GetPage(doc *goquery.Document, opts Options) *Page {
 	return &Page{
 		Title:       GetPageTitle(doc),
 		Link:        GetPageLink(doc),
-		Description: GetPageDescriptionHTML(doc),
-		ImageURL:    GetPageImageURL(doc),
-		Posts:       GetPosts(doc),
+		Description: GetPageDescriptionHTML(doc, opts),
+		ImageURL:    opts.Media.ProxyURL(GetPageImageURL(doc)),
+		Counters:    GetPageCounters(doc),
+		Verified:    GetPageVerified(doc),
+		Posts:       GetPosts(doc, opts),
 	}
 }
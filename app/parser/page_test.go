@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	"github.com/stretchr/testify/assert"
 	"strings"
@@ -20,6 +21,13 @@ const testPageHTML = `
 	<a href="https://t.me/telegram">@telegram</a>
 </div>
 <div class="tgme_channel_info_description">Some <b>page</b> description</div>
+<div class="tgme_channel_info_counters">
+	<div class="tgme_channel_info_counter"><span class="counter_value">12.3K</span> <span class="counter_type">subscribers</span></div>
+	<div class="tgme_channel_info_counter"><span class="counter_value">1.2K</span> <span class="counter_type">photos</span></div>
+	<div class="tgme_channel_info_counter"><span class="counter_value">57</span> <span class="counter_type">videos</span></div>
+	<div class="tgme_channel_info_counter"><span class="counter_value">1</span> <span class="counter_type">link</span></div>
+	<div class="tgme_channel_info_counter"><span class="counter_value">wrong</span> <span class="counter_type">files</span></div>
+</div>
 </body></html>
 `
 
@@ -50,7 +58,7 @@ func TestGetPageDescription(t *testing.T) {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testPageHTML))
 	assert.Nil(t, err)
 
-	description := GetPageDescriptionHTML(doc)
+	description := GetPageDescriptionHTML(doc, Options{})
 	assert.Equal(t, description, "Some <b>page</b> description")
 }
 
@@ -73,10 +81,51 @@ func TestGetPage(t *testing.T) {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testPageHTML))
 	assert.Nil(t, err)
 
-	page := GetPage(doc)
+	page := GetPage(doc, Options{})
 	assert.Equal(t, page.Title, "Some title")
 	assert.Equal(t, page.Link, "https://t.me/telegram")
 	assert.Equal(t, page.Description, "Some <b>page</b> description")
 	assert.Equal(t, page.ImageURL, "https://cdn4.cdn-telegram.org/file/img.jpg")
+	assert.Equal(t, Counters{Subscribers: 12300, Photos: 1200, Videos: 57, Links: 1}, page.Counters)
+	assert.True(t, page.Verified)
 	assert.Nil(t, page.Posts)
 }
+
+func TestGetPageCounters(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(``))
+	assert.Nil(t, err)
+	assert.Equal(t, Counters{}, GetPageCounters(doc))
+	assert.False(t, GetPageVerified(doc))
+}
+
+func TestParseCounter(t *testing.T) {
+	tbl := []struct {
+		inp string
+		out int
+		err error
+	}{
+		{"987", 987, nil},
+		{" 1 234 ", 1234, nil},
+		{"1,234", 1234, nil},
+		{"12.3K", 12300, nil},
+		{"1.25m", 1250000, nil},
+		{"2B", 2000000000, nil},
+		{"", 0, fmt.Errorf("can't parse counter: \"\"")},
+		{"K", 0, fmt.Errorf("can't parse counter: \"K\"")},
+		{"-5", 0, fmt.Errorf("can't parse counter: \"-5\"")},
+		{"NaN", 0, fmt.Errorf("can't parse counter: \"NaN\"")},
+		{"inf", 0, fmt.Errorf("can't parse counter: \"inf\"")},
+		{"+Infinity", 0, fmt.Errorf("can't parse counter: \"+Infinity\"")},
+		{"1e30", 0, fmt.Errorf("can't parse counter: \"1e30\"")},
+		{"1E3K", 0, fmt.Errorf("can't parse counter: \"1E3K\"")},
+		{"0x10", 0, fmt.Errorf("can't parse counter: \"0x10\"")},
+		{"1.", 0, fmt.Errorf("can't parse counter: \"1.\"")},
+		{"9999999999999B", 0, fmt.Errorf("can't parse counter: \"9999999999999B\"")},
+		{"99999999999999999999", 0, fmt.Errorf("can't parse counter: \"99999999999999999999\"")},
+	}
+	for _, tb := range tbl {
+		value, err := ParseCounter(tb.inp)
+		assert.Equal(t, tb.err, err)
+		assert.Equal(t, tb.out, value)
+	}
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strings"
+)
+
+// Block elements are never wrapped in paragraphs
+var blockAtoms = map[atom.Atom]bool{
+	atom.Pre: true, atom.Blockquote: true, atom.Details: true, atom.Ul: true, atom.Ol: true, atom.Hr: true, atom.Table: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+}
+
+// Containers are flattened, their content is split into paragraphs
+var containerAtoms = map[atom.Atom]bool{atom.P: true, atom.Div: true}
+
+// paragraphBuilder groups inline nodes into paragraphs
+type paragraphBuilder struct {
+	blocks []string
+	inline []*html.Node
+}
+
+// BuildParagraphs groups inline content of the HTML into <p> paragraphs.
+// Double <br> breaks the paragraph, single <br> is kept as a line break.
+// Block elements like <pre> and <blockquote> are kept outside of paragraphs.
+func BuildParagraphs(text string) string {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(text), context)
+	if err != nil {
+		return ""
+	}
+	b := &paragraphBuilder{}
+	b.addNodes(nodes)
+	b.flush()
+	return strings.Join(b.blocks, "\n")
+}
+
+func (b *paragraphBuilder) addNodes(nodes []*html.Node) {
+	for i := 0; i < len(nodes); i++ {
+		n := nodes[i]
+		switch {
+		case n.Type == html.ElementNode && n.DataAtom == atom.Br:
+			// Skip whitespace up to the next node to detect double <br>
+			next := i + 1
+			for next < len(nodes) && isBlank(nodes[next]) {
+				next++
+			}
+			if next < len(nodes) && nodes[next].Type == html.ElementNode && nodes[next].DataAtom == atom.Br {
+				// Paragraph break, skip all the following line breaks
+				for next < len(nodes) && (isBlank(nodes[next]) || nodes[next].DataAtom == atom.Br) {
+					next++
+				}
+				b.flush()
+				i = next - 1
+				continue
+			}
+			b.inline = append(b.inline, n)
+		case n.Type == html.ElementNode && containerAtoms[n.DataAtom]:
+			b.flush()
+			b.addNodes(getChildren(n))
+			b.flush()
+		case n.Type == html.ElementNode && blockAtoms[n.DataAtom]:
+			b.flush()
+			b.blocks = append(b.blocks, renderNodes([]*html.Node{n}))
+		case n.Type == html.TextNode || n.Type == html.ElementNode:
+			b.inline = append(b.inline, n)
+		}
+	}
+}
+
+// flush wraps collected inline nodes into paragraph
+func (b *paragraphBuilder) flush() {
+	inline := b.inline
+	b.inline = nil
+
+	// Trim blank nodes and line breaks at both ends
+	for len(inline) > 0 && (isBlank(inline[0]) || inline[0].DataAtom == atom.Br) {
+		inline = inline[1:]
+	}
+	for len(inline) > 0 && (isBlank(inline[len(inline)-1]) || inline[len(inline)-1].DataAtom == atom.Br) {
+		inline = inline[:len(inline)-1]
+	}
+	if len(inline) == 0 {
+		return
+	}
+	b.blocks = append(b.blocks, "<p>"+strings.TrimSpace(renderNodes(inline))+"</p>")
+}
+
+// isBlank reports whether the node is whitespace text
+func isBlank(n *html.Node) bool {
+	return n.Type == html.TextNode && strings.TrimSpace(n.Data) == ""
+}
+
+func getChildren(n *html.Node) []*html.Node {
+	var children []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		children = append(children, c)
+	}
+	return children
+}
+
+// renderNodes returns HTML of the nodes
+func renderNodes(nodes []*html.Node) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		if err := html.Render(&sb, n); err != nil {
+			return ""
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuildParagraphs(t *testing.T) {
+	tbl := []struct {
+		inp string
+		out string
+	}{
+		{"", ""},
+		{"  ", ""},
+		{"Text", "<p>Text</p>"},
+		{"Line 1<br/>Line 2", "<p>Line 1<br/>Line 2</p>"},
+		{"Par 1<br/><br/>Par 2", "<p>Par 1</p>\n<p>Par 2</p>"},
+		{"Par 1<br/> <br/><br/>Par 2<br/>", "<p>Par 1</p>\n<p>Par 2</p>"},
+		{"<br/>Text<br/><br/>", "<p>Text</p>"},
+		// Line breaks inside inline elements are kept as is
+		{`<b>Bold<br/><br/>text</b> after`, "<p><b>Bold<br/><br/>text</b> after</p>"},
+		// Block elements are never wrapped
+		{"Before<pre>code\n  line</pre>After", "<p>Before</p>\n<pre>code\n  line</pre>\n<p>After</p>"},
+		{"<blockquote>Quote<br/>line</blockquote>", "<blockquote>Quote<br/>line</blockquote>"},
+		{"<details><summary>Spoiler</summary>secret</details> text", "<details><summary>Spoiler</summary>secret</details>\n<p>text</p>"},
+		// Containers are flattened
+		{"<p>Par 1</p><p>Par 2<br/><br/>Par 3</p>", "<p>Par 1</p>\n<p>Par 2</p>\n<p>Par 3</p>"},
+		// Not closed tags are fixed
+		{"<b>Bold", "<p><b>Bold</b></p>"},
+	}
+	for _, tb := range tbl {
+		assert.Equal(t, tb.out, BuildParagraphs(tb.inp))
+	}
+}
@@ -4,12 +4,28 @@ package parser
 import (
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
-	"log"
 	"net/http"
 	"net/url"
 	"strings"
 )
 
+// Options defines how the channel page is parsed
+type Options struct {
+	Title TitleOptions
+	// Policy is the HTML sanitizer policy, strict policy is used if nil
+	Policy *Policy
+	// Entities defines how spoilers and other Telegram entities are rendered
+	Entities EntityOptions
+	// Links defines how t.me links in the post text and page description are rewritten
+	Links LinkOptions
+	// Media defines how image, video and avatar URLs are rewritten
+	Media MediaOptions
+	// Kinds maps post kinds to actions, service messages and polls are described and others included by default
+	Kinds map[PostKind]KindAction
+	// Client makes the page requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
 // GetChannelWebURL returns the channel web url based on the channel name
 func GetChannelWebURL(chName string) string {
 	// Remove @ from chName name
@@ -32,19 +48,23 @@ func GetChannelWebURL(chName string) string {
 	return ""
 }
 
-// Parse returns the page object
-func Parse(chName string) (*Page, error) {
+// LoadDocument requests the channel web preview with the client and returns its HTML document,
+// document URL is the final URL after redirects
+func LoadDocument(chName string, client *http.Client) (*goquery.Document, error) {
 	// Build web url
 	channelURL := GetChannelWebURL(chName)
+	if client == nil {
+		client = http.DefaultClient
+	}
 
 	// Request the HTML page.
-	res, err := http.Get(channelURL) //nolint:gosec // tolerate security risk
+	res, err := client.Get(channelURL) //nolint:gosec // tolerate security risk
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("can't load %s: %w", channelURL, err)
 	}
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status code error: %s", res.Status)
+	defer res.Body.Close() // nolint
+	if err := GetStatusError(res.StatusCode); err != nil {
+		return nil, fmt.Errorf("can't load %s: %w", channelURL, err)
 	}
 
 	// Load the HTML document
@@ -52,5 +72,18 @@ func Parse(chName string) (*Page, error) {
 	if err != nil {
 		return nil, fmt.Errorf("can't parse HTML: %w", err)
 	}
-	return GetPage(doc), nil
+	doc.Url = res.Request.URL
+	return doc, nil
+}
+
+// Parse returns the page object
+func Parse(chName string, opts Options) (*Page, error) {
+	doc, err := LoadDocument(chName, opts.Client)
+	if err != nil {
+		return nil, err
+	}
+	if err := GetPageError(doc, doc.Url); err != nil {
+		return nil, fmt.Errorf("can't parse %s: %w", chName, err)
+	}
+	return GetPage(doc, opts), nil
 }
@@ -1,10 +1,21 @@
 package parser
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 )
 
+// roundTripFunc serves the requests with the function instead of network
+type roundTripFunc func(req *http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
 func TestGetChannelWebURL(t *testing.T) {
 	tbl := []struct {
 		chName string
@@ -21,3 +32,27 @@ func TestGetChannelWebURL(t *testing.T) {
 		assert.Equal(t, tb.chURL, url)
 	}
 }
+
+func TestLoadDocument(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Request: req,
+			Body: io.NopCloser(strings.NewReader(`<div class="tgme_page_title">Private</div>`))}
+		switch req.URL.Path {
+		case "/s/private":
+			res.StatusCode = http.StatusFound
+			res.Header.Set("Location", "https://t.me/private")
+		case "/s/missing":
+			res.StatusCode = http.StatusNotFound
+		}
+		return res
+	})}
+
+	// Document URL is the final URL after redirects
+	doc, err := LoadDocument("private", client)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://t.me/private", doc.Url.String())
+	assert.Equal(t, "Private", doc.Find(".tgme_page_title").Text())
+
+	_, err = LoadDocument("missing", client)
+	assert.True(t, errors.Is(err, ErrChannelNotFound))
+}
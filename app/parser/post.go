@@ -5,82 +5,126 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"log"
 	"net/url"
-	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // Post represents a post from the telegram channel
 type Post struct {
-	Title   string
-	Text    string
-	Link    string
-	ID      string
+	Title string
+	Text  string
+	Link  string
+	// ID is the post identifier as in data-post attribute, e.g. "telegram/1"
+	ID string
+	// Kind is the message kind, e.g. regular or service
+	Kind PostKind
+	// Channel is the channel username and MessageID is the numeric message ID parsed from ID
+	Channel   string
+	MessageID int
+	// ViewToken is the internal view token from data-view attribute
+	ViewToken string
+	// Views is the post views counter, 0 if not shown
+	Views   int
 	Created time.Time
 	Videos  []string
 	Images  []string
+	// VideoThumbs are the preview images of the videos
+	VideoThumbs []string
+	// SourceMedia are the original URLs of the images and videos replaced by mirrored ones, nil if not mirrored
+	SourceMedia []string
+	// Sticker is the sticker attached to the post or nil
+	Sticker *Sticker
+	// ForwardedFrom is the link to the original message if the post is forwarded
+	ForwardedFrom string
+	// Markdown and PlainText are the post text renderings for non-HTML consumers
+	Markdown  string
+	PlainText string
 }
 
-// GetPosts returns all posts from the page
-func GetPosts(doc *goquery.Document) []*Post {
+// GetPosts returns all posts from the page, messages of the same media group are merged into one post
+func GetPosts(doc *goquery.Document, opts Options) []*Post {
 	var posts []*Post
+	var group *albumGroup
 
 	doc.Find(".tgme_widget_message_wrap").Each(func(_ int, s *goquery.Selection) {
-		postLink := GetPostLink(s)
-		text := GetPostTextHTML(s)
-		posts = append(posts, &Post{
-			Title:   GetPostTitle(text),
-			Text:    text,
-			Link:    postLink,
-			Created: GetPostCreated(s),
-			Videos:  GetVideos(s),
-			Images:  GetImages(s),
-		})
+		post := getPost(s, opts)
+		switch getKindAction(post.Kind, opts.Kinds) {
+		case KindSkip:
+			return
+		case KindDescribe:
+			title, text := GetPostDescription(s, post.Kind)
+			describePost(post, title, text, opts)
+		}
+		if post.Kind == KindRegular && post.Text == "" {
+			// Document or audio file without caption is described by its title to not leave the item blank
+			title, text := GetDocumentDescription(s)
+			describePost(post, title, text, opts)
+		}
+		if group != nil && group.accepts(s, post) {
+			group.add(s, post, opts)
+			return
+		}
+		group = newAlbumGroup(s, post)
+		posts = append(posts, post)
 	})
 	return posts
 }
 
-// GetPostTitle returns the post title
-func GetPostTitle(text string) string {
-	// Get only string before the first <br>
-	title := strings.Split(text, "<br")[0]
-
-	// Remove all tags using regexp
-	title = regexp.MustCompile("<[^>]*>").ReplaceAllString(title, "")
+// getPost returns the post of the message
+func getPost(s *goquery.Selection, opts Options) *Post {
+	postLink := GetPostLink(s)
+	postID := GetPostID(s)
+	channel, messageID, err := ParsePostID(postID)
+	if err != nil {
+		log.Printf("[ERROR] %v, fallback with empty channel and message ID", err)
+	}
+	text := GetPostTextHTML(s, opts)
+	return &Post{
+		Title:     BuildPostTitle(s, text, opts.Title),
+		Text:      text,
+		Link:      postLink,
+		ID:        postID,
+		Kind:      GetPostKind(s),
+		Channel:   channel,
+		MessageID: messageID,
+		ViewToken: GetPostViewToken(s),
+		Views:     GetPostViews(s),
+		Created:   GetPostCreated(s),
+		Videos:    opts.Media.proxyURLs(GetVideos(s)),
+		Images:    opts.Media.proxyURLs(GetImages(s)),
+		Sticker:   opts.Media.proxySticker(GetSticker(s)),
 
-	// Remove all new lines
-	title = strings.TrimSpace(title)
+		VideoThumbs: opts.Media.proxyURLs(GetVideoThumbs(s)),
 
-	// Shorten the title to 30 characters
-	return ShortenText(title, 30)
+		ForwardedFrom: GetPostForwardedFrom(s),
+		Markdown:      HTMLToMarkdown(text),
+		PlainText:     HTMLToText(text),
+	}
 }
 
-// GetPostTextHTML returns the post text as HTML
-func GetPostTextHTML(s *goquery.Selection) string {
-	s = s.Find(".tgme_widget_message_text")
-
-	html := GetSafeHTML(s)
-
-	var paragraphs []string
-
-	// split by <br>
-	parts := strings.Split(html, "<br/>")
-	if len(parts) <= 1 {
-		parts = strings.Split(html, "<br>")
+// describePost replaces the post title and text with the description, empty values are kept
+func describePost(post *Post, title, text string, opts Options) {
+	if title != "" {
+		post.Title = ShortenText(title, opts.Title.getMaxLength())
 	}
-	for _, part := range parts {
-		// ignore empty paragraphs
-		if part != "" {
-			paragraphs = append(paragraphs, fmt.Sprintf("<p>%s</p>", strings.TrimSpace(part)))
-		}
+	if text != "" {
+		post.Text = text
+		post.Markdown = HTMLToMarkdown(text)
+		post.PlainText = HTMLToText(text)
 	}
-	formattedText := strings.Join(paragraphs, "\n")
+}
 
-	// replace https://t.me/... with https://t.me/s/...
-	formattedText = strings.ReplaceAll(formattedText, "https://t.me/", "https://t.me/s/")
+// GetPostTitle returns the post title: the first line shortened to 30 characters
+func GetPostTitle(text string) string {
+	return ShortenText(getFirstLine(text), defaultTitleMaxLength)
+}
 
-	return formattedText
+// GetPostTextHTML returns the post text as HTML
+func GetPostTextHTML(s *goquery.Selection, opts Options) string {
+	s = s.Find(".tgme_widget_message_text")
 
+	return BuildParagraphs(GetSafeHTML(s, opts))
 }
 
 // GetPostLink returns the post link
@@ -100,6 +144,80 @@ func GetPostLink(s *goquery.Selection) string {
 	return baseURL.ResolveReference(hrefURL).String()
 }
 
+// GetPostID returns the post identifier from data-post attribute, e.g. "telegram/1"
+func GetPostID(s *goquery.Selection) string {
+	postID, _ := s.Find(".tgme_widget_message").Attr("data-post")
+	return postID
+}
+
+// GetPostViewToken returns the internal view token from data-view attribute
+func GetPostViewToken(s *goquery.Selection) string {
+	token, _ := s.Find(".tgme_widget_message").Attr("data-view")
+	return token
+}
+
+// ParsePostID splits the post identifier like "telegram/1" into the channel username and message ID
+func ParsePostID(postID string) (string, int, error) {
+	channel, id, found := strings.Cut(postID, "/")
+	if !found || channel == "" {
+		return "", 0, fmt.Errorf("can't parse post ID: %q", postID)
+	}
+	messageID, err := strconv.Atoi(id)
+	if err != nil || messageID <= 0 {
+		return "", 0, fmt.Errorf("can't parse message ID of post %q", postID)
+	}
+	return channel, messageID, nil
+}
+
+// NativeURL returns the post link opening in Telegram app, e.g. https://t.me/telegram/1
+func (p *Post) NativeURL() string {
+	return p.buildURL("https://t.me/", "")
+}
+
+// PreviewURL returns the post link on the channel web preview, e.g. https://t.me/s/telegram/1
+func (p *Post) PreviewURL() string {
+	return p.buildURL("https://t.me/s/", "")
+}
+
+// EmbedURL returns the post widget link for embedding, e.g. https://t.me/telegram/1?embed=1
+func (p *Post) EmbedURL() string {
+	return p.buildURL("https://t.me/", "embed=1")
+}
+
+// buildURL returns the post URL with the base and query or empty string if the post has no message ID
+func (p *Post) buildURL(base, query string) string {
+	if p.Channel == "" || p.MessageID == 0 {
+		return ""
+	}
+	u := base + url.PathEscape(p.Channel) + "/" + strconv.Itoa(p.MessageID)
+	if query != "" {
+		u += "?" + query
+	}
+	return u
+}
+
+// GetPostForwardedFrom returns the link to the original message of the forwarded post
+func GetPostForwardedFrom(s *goquery.Selection) string {
+	if link, exists := s.Find("a.tgme_widget_message_forwarded_from_name").Attr("href"); exists {
+		return link
+	}
+	return ""
+}
+
+// GetPostViews returns the post views counter, 0 if it's not shown
+func GetPostViews(s *goquery.Selection) int {
+	views := s.Find(".tgme_widget_message_views").First()
+	if views.Length() == 0 {
+		return 0
+	}
+	value, err := ParseCounter(views.Text())
+	if err != nil {
+		log.Printf("[ERROR] failed to parse post views: %v, fallback with 0", err)
+		return 0
+	}
+	return value
+}
+
 // GetPostCreated returns the post created datetime
 func GetPostCreated(s *goquery.Selection) time.Time {
 	created, exists := s.Find(".tgme_widget_message_date time").Attr("datetime")
@@ -119,6 +237,9 @@ func GetPostCreated(s *goquery.Selection) time.Time {
 func GetVideos(s *goquery.Selection) []string {
 	var videos []string
 	s.Find("video").Each(func(_ int, s *goquery.Selection) {
+		if isSticker(s) {
+			return
+		}
 		videoURL, exists := s.Attr("src")
 		if exists {
 			videos = append(videos, videoURL)
@@ -127,6 +248,17 @@ func GetVideos(s *goquery.Selection) []string {
 	return videos
 }
 
+// GetVideoThumbs returns preview images of all videos from the post
+func GetVideoThumbs(s *goquery.Selection) []string {
+	var thumbs []string
+	s.Find(".tgme_widget_message_video_thumb, .tgme_widget_message_roundvideo_thumb").Each(func(_ int, s *goquery.Selection) {
+		if imageURL := extractImageURLFromStyle(s); imageURL != "" {
+			thumbs = append(thumbs, imageURL)
+		}
+	})
+	return thumbs
+}
+
 // extractImageURLFromStyle extracts image URL from the style attribute
 func extractImageURLFromStyle(s *goquery.Selection) string {
 	style, exists := s.Attr("style")
@@ -157,6 +289,9 @@ func GetImages(s *goquery.Selection) []string {
 		}
 	})
 	s.Find("img").Each(func(_ int, s *goquery.Selection) {
+		if isSticker(s) {
+			return
+		}
 		if imageURL, exists := s.Attr("src"); exists {
 			images = append(images, imageURL)
 		}
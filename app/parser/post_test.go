@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	"github.com/stretchr/testify/assert"
 	"strings"
@@ -119,6 +120,15 @@ func TestGetVideos(t *testing.T) {
 	assert.Equal(t, 0, len(videos))
 }
 
+func TestGetVideoThumbs(t *testing.T) {
+	s := getSelection()
+	assert.Equal(t, []string{"https://cdn4.cdn-telegram.org/file/123"}, GetVideoThumbs(s))
+
+	// Empty post
+	s = getEmptySelection()
+	assert.Equal(t, 0, len(GetVideoThumbs(s)))
+}
+
 func TestGetCreated(t *testing.T) {
 	s := getSelection()
 	created := GetPostCreated(s)
@@ -130,6 +140,11 @@ func TestGetCreated(t *testing.T) {
 	assert.Equal(t, time.Now().Format(time.DateTime), created.Format(time.DateTime))
 }
 
+func TestGetPostViews(t *testing.T) {
+	assert.Equal(t, 28600, GetPostViews(getSelection()))
+	assert.Equal(t, 0, GetPostViews(getEmptySelection()))
+}
+
 func TestGetPostLink(t *testing.T) {
 	s := getSelection()
 	link := GetPostLink(s)
@@ -141,17 +156,84 @@ func TestGetPostLink(t *testing.T) {
 	assert.Equal(t, "", link)
 }
 
+func TestGetPostID(t *testing.T) {
+	assert.Equal(t, "telegram/1", GetPostID(getSelection()))
+	assert.Equal(t, "", GetPostID(getEmptySelection()))
+	assert.Equal(t, "", GetPostViewToken(getEmptySelection()))
+}
+
+func TestParsePostID(t *testing.T) {
+	tbl := []struct {
+		inp       string
+		channel   string
+		messageID int
+		err       error
+	}{
+		{"telegram/1", "telegram", 1, nil},
+		{"durov/12345", "durov", 12345, nil},
+		{"", "", 0, fmt.Errorf("can't parse post ID: \"\"")},
+		{"/1", "", 0, fmt.Errorf("can't parse post ID: \"/1\"")},
+		{"telegram/abc", "", 0, fmt.Errorf("can't parse message ID of post \"telegram/abc\"")},
+		{"telegram/0", "", 0, fmt.Errorf("can't parse message ID of post \"telegram/0\"")},
+	}
+	for _, tb := range tbl {
+		channel, messageID, err := ParsePostID(tb.inp)
+		assert.Equal(t, tb.err, err)
+		assert.Equal(t, tb.channel, channel)
+		assert.Equal(t, tb.messageID, messageID)
+	}
+}
+
+func TestPost_URLs(t *testing.T) {
+	post := &Post{Channel: "telegram", MessageID: 1}
+	assert.Equal(t, "https://t.me/telegram/1", post.NativeURL())
+	assert.Equal(t, "https://t.me/s/telegram/1", post.PreviewURL())
+	assert.Equal(t, "https://t.me/telegram/1?embed=1", post.EmbedURL())
+
+	post = &Post{}
+	assert.Equal(t, "", post.NativeURL())
+	assert.Equal(t, "", post.PreviewURL())
+	assert.Equal(t, "", post.EmbedURL())
+}
+
 func TestGetPostTextHTML(t *testing.T) {
 	s := getSelection()
-	text := GetPostTextHTML(s)
+	text := GetPostTextHTML(s, Options{})
 	assert.Equal(t, "<p>Test text</p>", text)
 
 	// Empty post should return ""
 	s = getEmptySelection()
-	text = GetPostTextHTML(s)
+	text = GetPostTextHTML(s, Options{})
 	assert.Equal(t, "", text)
 }
 
+func TestGetPostTextHTML_Paragraphs(t *testing.T) {
+	const html = `<body><div class="tgme_widget_message_text">
+		<b>Title</b><br/><br/>Line 1<br/>Line 2 <a href="https://t.me/telegram">link<br/>text</a><br/><br/><br/>Last
+	</div></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	text := GetPostTextHTML(doc.Find("body"), Options{})
+	assert.Equal(t, "<p><b>Title</b></p>\n"+
+		`<p>Line 1<br/>Line 2 <a href="https://t.me/s/telegram">link<br/>text</a></p>`+"\n"+
+		"<p>Last</p>", text)
+}
+
+func TestGetPostTextHTML_Links(t *testing.T) {
+	const html = `<body><div class="tgme_widget_message_text">
+		<a href="https://t.me/telegram/1">post</a> <a href="https://t.me/+AbCdEf">invite</a> <a href="https://t.me/s/telegram">channel</a>
+	</div></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	text := GetPostTextHTML(doc.Find("body"), Options{})
+	assert.Equal(t, `<p><a href="https://t.me/s/telegram/1">post</a> <a href="https://t.me/+AbCdEf">invite</a> <a href="https://t.me/s/telegram">channel</a></p>`, text)
+
+	text = GetPostTextHTML(doc.Find("body"), Options{Links: LinkOptions{Mode: LinkNative}})
+	assert.Equal(t, `<p><a href="https://t.me/telegram/1">post</a> <a href="https://t.me/+AbCdEf">invite</a> <a href="https://t.me/s/telegram">channel</a></p>`, text)
+}
+
 func TestGetPostTitle(t *testing.T) {
 	tbl := []struct {
 		inp string
@@ -174,12 +256,43 @@ func TestGetPosts(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
-	posts := GetPosts(doc)
+	posts := GetPosts(doc, Options{})
 	assert.Equal(t, 1, len(posts))
 	assert.Equal(t, "<p>Test text</p>", posts[0].Text)
 	assert.Equal(t, "https://t.me/s/telegram/1", posts[0].Link)
 	assert.Equal(t, "15 Dec 23 16:29 +0000", posts[0].Created.Format(time.RFC822Z))
 	assert.Equal(t, "Test text", posts[0].Title)
+	assert.Equal(t, "Test text", posts[0].Markdown)
+	assert.Equal(t, "Test text", posts[0].PlainText)
 	assert.Equal(t, 3, len(posts[0].Images))
 	assert.Equal(t, 1, len(posts[0].Videos))
+	assert.Equal(t, "telegram/1", posts[0].ID)
+	assert.Equal(t, "telegram", posts[0].Channel)
+	assert.Equal(t, 1, posts[0].MessageID)
+	assert.Equal(t, "eyJjIjotMTA5Njg3MzUxMiwicCI6IjQzMTZnIiwidCI6MTcwMjgyMjEyOCwiaCI6ImNjNzg5ODgxY2ZkMTIzNTY4NiJ9", posts[0].ViewToken)
+}
+
+func TestGetPostForwardedFrom(t *testing.T) {
+	const html = `<body><div class="tgme_widget_message_forwarded_from accent_color">Forwarded from 
+		<a class="tgme_widget_message_forwarded_from_name" href="https://t.me/durov/123"><span dir="auto">Pavel Durov</span></a>
+	</div></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+	assert.Equal(t, "https://t.me/durov/123", GetPostForwardedFrom(doc.Find("body")))
+
+	// Not forwarded post
+	assert.Equal(t, "", GetPostForwardedFrom(getSelection()))
+}
+
+func TestGetPostTextHTML_Code(t *testing.T) {
+	const html = `<body><div class="tgme_widget_message_text">Example:<br/><pre>if x {<br/>    return<br/>}</pre><br/>Done</div></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	text := GetPostTextHTML(doc.Find("body"), Options{Policy: FormattingPolicy()})
+	assert.Equal(t, "<p>Example:</p>\n<pre>if x {\n    return\n}</pre>\n<p>Done</p>", text)
+
+	// Strict policy keeps the code lines as line breaks
+	text = GetPostTextHTML(doc.Find("body"), Options{})
+	assert.Equal(t, "<p>Example:<br/>if x {<br/>    return<br/>}<br/>Done</p>", text)
 }
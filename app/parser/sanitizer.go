@@ -0,0 +1,176 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Policy defines the tags and attributes kept by the sanitizer
+type Policy struct {
+	// Tags maps allowed tags to their allowed attributes.
+	// Other tags are replaced by their content.
+	Tags map[string][]string
+	// URLSchemes are the allowed schemes of href and src attributes.
+	// Relative URLs are always allowed.
+	URLSchemes []string
+}
+
+// Policy presets
+const (
+	PolicyStrict     = "strict"
+	PolicyFormatting = "formatting"
+	PolicyRich       = "rich"
+)
+
+var defaultURLSchemes = []string{"http", "https", "mailto"}
+
+// Tags removed with their content
+const droppedTags = "script, style, iframe, object, embed, noscript, template, svg, math"
+
+// URL attributes checked against allowed schemes
+var urlAttrs = map[string]bool{"href": true, "src": true}
+
+var policyTagRe = regexp.MustCompile(`^([a-z][a-z0-9-]*)(?:\[([a-z0-9-| ]*)])?$`)
+
+// Characters ignored by browsers in URLs, e.g. "java\tscript:"
+var urlIgnoredRe = regexp.MustCompile(`[\x00-\x20\x7f]+`)
+
+// StrictPolicy keeps only links, bold, italic, underline, strikethrough, quotes and line breaks
+func StrictPolicy() *Policy {
+	return &Policy{
+		Tags: map[string][]string{
+			"a":          {"href"},
+			"b":          nil,
+			"i":          nil,
+			"u":          nil,
+			"s":          nil,
+			"blockquote": nil,
+			"br":         nil,
+		},
+		URLSchemes: defaultURLSchemes,
+	}
+}
+
+// FormattingPolicy keeps the strict policy tags, their variants and code blocks
+func FormattingPolicy() *Policy {
+	p := StrictPolicy()
+	for _, tag := range []string{"strong", "em", "del", "code", "pre"} {
+		p.Tags[tag] = nil
+	}
+	return p
+}
+
+// RichPolicy keeps formatting, paragraphs, lists and images
+func RichPolicy() *Policy {
+	p := FormattingPolicy()
+	for _, tag := range []string{"p", "ul", "ol", "li", "h1", "h2", "h3", "h4", "h5", "h6", "hr"} {
+		p.Tags[tag] = nil
+	}
+	p.Tags["a"] = []string{"href", "title"}
+	p.Tags["img"] = []string{"src", "alt", "title"}
+	return p
+}
+
+// ParsePolicy returns the preset policy by name or the policy with custom allowlist,
+// e.g. "a[href|title], b, i, code"
+func ParsePolicy(str string) (*Policy, error) {
+	switch strings.TrimSpace(str) {
+	case "", PolicyStrict:
+		return StrictPolicy(), nil
+	case PolicyFormatting:
+		return FormattingPolicy(), nil
+	case PolicyRich:
+		return RichPolicy(), nil
+	}
+
+	p := &Policy{Tags: make(map[string][]string), URLSchemes: defaultURLSchemes}
+	for _, item := range strings.Split(str, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		m := policyTagRe.FindStringSubmatch(item)
+		if m == nil {
+			return nil, fmt.Errorf("can't parse policy tag: %s", item)
+		}
+		var attrs []string
+		for _, attr := range strings.Split(m[2], "|") {
+			if attr = strings.TrimSpace(attr); attr != "" {
+				attrs = append(attrs, attr)
+			}
+		}
+		p.Tags[m[1]] = attrs
+	}
+	return p, nil
+}
+
+// withTags returns a copy of the policy allowing the tags with attributes,
+// the attributes are added to the tags already allowed by the policy
+func (p *Policy) withTags(tags map[string][]string) *Policy {
+	if len(tags) == 0 {
+		return p
+	}
+	clone := &Policy{Tags: make(map[string][]string, len(p.Tags)+len(tags)), URLSchemes: p.URLSchemes}
+	for tag, attrs := range p.Tags {
+		clone.Tags[tag] = attrs
+	}
+	for tag, attrs := range tags {
+		merged := slices.Clone(clone.Tags[tag])
+		for _, attr := range attrs {
+			if !slices.Contains(merged, attr) {
+				merged = append(merged, attr)
+			}
+		}
+		clone.Tags[tag] = merged
+	}
+	return clone
+}
+
+// isAllowedURL checks the URL scheme against the allowed ones
+func (p *Policy) isAllowedURL(rawURL string) bool {
+	u, err := url.Parse(urlIgnoredRe.ReplaceAllString(rawURL, ""))
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		return true
+	}
+	for _, scheme := range p.URLSchemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sanitize removes tags and attributes not allowed by the policy
+func Sanitize(s *goquery.Selection, p *Policy) *goquery.Selection {
+	// Clone the selection to avoid modifying the original
+	s = s.Clone()
+
+	s.Find(droppedTags).Remove()
+
+	s.Find("*").Each(func(_ int, s *goquery.Selection) {
+		tag := goquery.NodeName(s)
+		allowedAttrs, ok := p.Tags[tag]
+		if !ok {
+			// Keep the content of not allowed tag
+			s.ReplaceWithSelection(s.Contents())
+			return
+		}
+		// Filter attributes in place
+		node := s.Get(0)
+		attrs := node.Attr[:0]
+		for _, attr := range node.Attr {
+			if slices.Contains(allowedAttrs, attr.Key) && (!urlAttrs[attr.Key] || p.isAllowedURL(attr.Val)) {
+				attrs = append(attrs, attr)
+			}
+		}
+		node.Attr = attrs
+	})
+	return s
+}
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func sanitizeHTML(t *testing.T, html string, p *Policy) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	body := doc.Find("body")
+	sanitized := Sanitize(body, p)
+	assert.NotEqualf(t, body, sanitized, "body and sanitized should not be equal")
+
+	sanitizedHTML, err := sanitized.Html()
+	assert.Nil(t, err)
+	return sanitizedHTML
+}
+
+func TestSanitize(t *testing.T) {
+	tbl := []struct {
+		html string
+		p    *Policy
+		out  string
+	}{
+		// Attributes are removed from kept tags
+		{`<b style="color:red" class="x">b</b><a href="https://t.me" onclick="alert(1)" title="t">a</a>`, StrictPolicy(),
+			`<b>b</b><a href="https://t.me">a</a>`},
+		// Unsafe URL schemes are removed
+		{`<a href="javascript:alert(1)">a</a><a href=" JavaScript:alert(1)">b</a><a href="java&#x09;script:alert(1)">c</a>`, StrictPolicy(),
+			`<a>a</a><a>b</a><a>c</a>`},
+		{`<a href="mailto:a@b.c">a</a><a href="/relative">b</a><a href="tg://resolve?domain=x">c</a>`, StrictPolicy(),
+			`<a href="mailto:a@b.c">a</a><a href="/relative">b</a><a>c</a>`},
+		// Not allowed tags are replaced by content keeping allowed children
+		{`<span class="x"><b>bold</b> text</span><u>u</u><code>c</code>`, StrictPolicy(),
+			`<b>bold</b> text<u>u</u>c`},
+		// Dangerous tags are removed with content
+		{`<script>alert(1)</script><style>b{}</style>text`, StrictPolicy(), `text`},
+		// Escaped text is not turned into tags
+		{`<span>&lt;script&gt;alert(1)&lt;/script&gt;</span>`, StrictPolicy(), `&lt;script&gt;alert(1)&lt;/script&gt;`},
+		{`<u>u</u><s>s</s><code>c</code><pre>p</pre><blockquote>q</blockquote><p>p</p>`, FormattingPolicy(),
+			`<u>u</u><s>s</s><code>c</code><pre>p</pre><blockquote>q</blockquote>p`},
+		{`<p>p</p><img src="https://t.me/i.jpg" onerror="alert(1)"><img src="data:image/png;base64,AAA">`, RichPolicy(),
+			`<p>p</p><img src="https://t.me/i.jpg"/><img/>`},
+	}
+	for _, tb := range tbl {
+		assert.Equal(t, tb.out, sanitizeHTML(t, tb.html, tb.p))
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tbl := []struct {
+		inp string
+		out *Policy
+		err error
+	}{
+		{"", StrictPolicy(), nil},
+		{"strict", StrictPolicy(), nil},
+		{"formatting", FormattingPolicy(), nil},
+		{"rich", RichPolicy(), nil},
+		{"a[href|title], b, CODE", &Policy{
+			Tags:       map[string][]string{"a": {"href", "title"}, "b": nil, "code": nil},
+			URLSchemes: []string{"http", "https", "mailto"},
+		}, nil},
+		{"a[href", nil, fmt.Errorf("can't parse policy tag: a[href")},
+	}
+	for _, tb := range tbl {
+		p, err := ParsePolicy(tb.inp)
+		assert.Equal(t, tb.err, err)
+		assert.Equal(t, tb.out, p)
+	}
+}
+
+func TestGetSafeHTML_Policy(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<body><code>x</code> <u>u</u></body>`))
+	assert.Nil(t, err)
+	body := doc.Find("body")
+
+	assert.Equal(t, `x <u>u</u>`, GetSafeHTML(body, Options{}))
+	assert.Equal(t, `<code>x</code> <u>u</u>`, GetSafeHTML(body, Options{Policy: FormattingPolicy()}))
+}
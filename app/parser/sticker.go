@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"html"
+	"strings"
+)
+
+// Telegram sticker markup: static, animated (.tgs) and video (.webm) stickers
+const stickerSelector = ".tgme_widget_message_sticker, .tgme_widget_message_tgsticker, .tgme_widget_message_videosticker"
+
+// Size of custom emoji images in pixels
+const customEmojiSize = "20"
+
+// Sticker is the sticker attached to the post
+type Sticker struct {
+	// URL of the sticker file: .webp, .tgs or .webm
+	URL string
+	// PreviewURL is the static image of the sticker
+	PreviewURL string
+	// Animated is true for .tgs and .webm stickers
+	Animated bool
+}
+
+// GetSticker returns the sticker attached to the post or nil
+func GetSticker(s *goquery.Selection) *Sticker {
+	sel := s.Find(stickerSelector).First()
+	if sel.Length() == 0 {
+		return nil
+	}
+	sticker := &Sticker{}
+	switch {
+	case sel.HasClass("tgme_widget_message_tgsticker"):
+		sticker.Animated = true
+		sticker.URL, _ = sel.Find(`source[type="application/x-tgsticker"]`).Attr("srcset")
+	case sel.HasClass("tgme_widget_message_videosticker"):
+		sticker.Animated = true
+		sticker.URL, _ = sel.Find("video").Attr("src")
+	default:
+		sticker.URL, _ = sel.Attr("data-webp")
+	}
+	sticker.PreviewURL = getStickerPreviewURL(sel)
+	if sticker.URL == "" {
+		sticker.URL = sticker.PreviewURL
+	}
+	if sticker.URL == "" {
+		return nil
+	}
+	return sticker
+}
+
+// getStickerPreviewURL returns the static image of the sticker from <img> or background style
+func getStickerPreviewURL(s *goquery.Selection) string {
+	if imageURL := extractImageURLFromStyle(s); imageURL != "" {
+		return imageURL
+	}
+	if imageURL, exists := s.Find("img").Attr("src"); exists {
+		return imageURL
+	}
+	var imageURL string
+	s.Find("[style]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		imageURL = extractImageURLFromStyle(s)
+		return imageURL == ""
+	})
+	return imageURL
+}
+
+// isSticker reports whether the element is a part of the sticker
+func isSticker(s *goquery.Selection) bool {
+	return s.Closest(stickerSelector).Length() > 0
+}
+
+// FixCustomEmoji replaces custom emoji with <img> of the emoji image.
+// Emoji without image is kept as is to be replaced with its text by FixEmoji.
+func FixCustomEmoji(s *goquery.Selection) *goquery.Selection {
+	// Clone the selection to avoid modifying the original
+	s = s.Clone()
+
+	s.Find("tg-emoji").Each(func(_ int, s *goquery.Selection) {
+		imageURL := extractImageURLFromStyle(s)
+		s.Find("[style]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if imageURL == "" {
+				imageURL = extractImageURLFromStyle(s)
+			}
+			return imageURL == ""
+		})
+		if imageURL == "" {
+			return
+		}
+		if strings.HasPrefix(imageURL, "//") {
+			imageURL = "https:" + imageURL
+		}
+		s.ReplaceWithHtml(`<img src="` + html.EscapeString(imageURL) + `" alt="` + html.EscapeString(strings.TrimSpace(s.Text())) +
+			`" width="` + customEmojiSize + `" height="` + customEmojiSize + `"/>`)
+	})
+	return s
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestGetSticker(t *testing.T) {
+	tbl := []struct {
+		html string
+		out  *Sticker
+	}{
+		{`<div class="tgme_widget_message_text">Text</div>`, nil},
+		{`<i class="tgme_widget_message_sticker js-sticker_image" style="background-image:url('https://cdn.example.com/sticker.webp')" data-webp="https://cdn.example.com/sticker.webp"></i>`,
+			&Sticker{URL: "https://cdn.example.com/sticker.webp", PreviewURL: "https://cdn.example.com/sticker.webp"}},
+		{`<div class="tgme_widget_message_tgsticker"><picture><source type="application/x-tgsticker" srcset="https://cdn.example.com/sticker.tgs"><img src="https://cdn.example.com/sticker.png"></picture></div>`,
+			&Sticker{URL: "https://cdn.example.com/sticker.tgs", PreviewURL: "https://cdn.example.com/sticker.png", Animated: true}},
+		{`<div class="tgme_widget_message_videosticker"><video src="https://cdn.example.com/sticker.webm"></video><i class="thumb" style="background-image:url('https://cdn.example.com/thumb.webp')"></i></div>`,
+			&Sticker{URL: "https://cdn.example.com/sticker.webm", PreviewURL: "https://cdn.example.com/thumb.webp", Animated: true}},
+		{`<div class="tgme_widget_message_tgsticker"></div>`, nil},
+	}
+	for _, tb := range tbl {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tb.html))
+		assert.Nil(t, err)
+		assert.Equal(t, tb.out, GetSticker(doc.Find("body")), tb.html)
+	}
+}
+
+func TestGetMedia_Sticker(t *testing.T) {
+	// Sticker files are not listed as post images and videos
+	const html = `<body><div class="tgme_widget_message_videosticker"><video src="sticker.webm"></video><img src="sticker.webp"></div></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+	assert.Nil(t, GetVideos(doc.Find("body")))
+	assert.Nil(t, GetImages(doc.Find("body")))
+}
+
+func TestFixCustomEmoji(t *testing.T) {
+	tbl := []struct {
+		html string
+		out  string
+	}{
+		{`<tg-emoji emoji-id="1"><i class="emoji" style="background-image:url('//cdn.example.com/emoji.webp')"><b>👍</b></i></tg-emoji>`,
+			`<img src="https://cdn.example.com/emoji.webp" alt="👍" width="20" height="20"/>`},
+		{`<tg-emoji emoji-id="1">👍</tg-emoji>`, `<tg-emoji emoji-id="1">👍</tg-emoji>`},
+		{`<i class="emoji" style="background-image:url('emoji.png')">👍</i>`, `<i class="emoji" style="background-image:url(&#39;emoji.png&#39;)">👍</i>`},
+	}
+	for _, tb := range tbl {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader("<body>" + tb.html + "</body>"))
+		assert.Nil(t, err)
+
+		body := doc.Find("body")
+		fixedBody := FixCustomEmoji(body)
+		assert.NotEqualf(t, body, fixedBody, "body and fixedBody should not be equal")
+
+		fixedBodyHTML, err := fixedBody.Html()
+		assert.Nil(t, err)
+		assert.Equal(t, tb.out, fixedBodyHTML)
+	}
+}
+
+func TestGetSafeHTML_CustomEmoji(t *testing.T) {
+	const html = `<body>Hi <tg-emoji emoji-id="1"><i class="emoji" style="background-image:url('https://cdn.example.com/emoji.webp')"><b>👍</b></i></tg-emoji> <tg-emoji>👋</tg-emoji></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+	body := doc.Find("body")
+
+	assert.Equal(t, `Hi 👍 👋`, GetSafeHTML(body, Options{}))
+	assert.Equal(t, `Hi <img src="https://cdn.example.com/emoji.webp" alt="👍" width="20" height="20"/> 👋`,
+		GetSafeHTML(body, Options{Entities: EntityOptions{CustomEmoji: true}}))
+}
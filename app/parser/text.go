@@ -0,0 +1,194 @@
+package parser
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var trailingSpaceRe = regexp.MustCompile(`[ \t]+\n`)
+var extraNewLinesRe = regexp.MustCompile(`\n{3,}`)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+)
+
+// markdownURLEscaper escapes the characters ending Markdown link destination
+var markdownURLEscaper = strings.NewReplacer(
+	` `, `%20`,
+	`(`, `%28`,
+	`)`, `%29`,
+)
+
+// textRenderer renders HTML nodes as Markdown or plain text
+type textRenderer struct {
+	markdown bool
+}
+
+// HTMLToMarkdown returns Markdown representation of the post HTML:
+// links as [text](url), bold, italic, code and paragraphs are preserved
+func HTMLToMarkdown(text string) string {
+	return renderText(text, &textRenderer{markdown: true})
+}
+
+// HTMLToText returns plain text representation of the post HTML with paragraphs preserved
+func HTMLToText(text string) string {
+	return renderText(text, &textRenderer{})
+}
+
+func renderText(text string, r *textRenderer) string {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(text), context)
+	if err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, n := range nodes {
+		sb.WriteString(r.render(n))
+	}
+	out := trailingSpaceRe.ReplaceAllString(sb.String(), "\n")
+	out = extraNewLinesRe.ReplaceAllString(out, "\n\n")
+	return strings.TrimSpace(out)
+}
+
+// render returns the node text with its children
+func (r *textRenderer) render(n *html.Node) string {
+	if n.Type == html.TextNode {
+		if r.markdown {
+			return markdownEscaper.Replace(n.Data)
+		}
+		return n.Data
+	}
+	if n.Type != html.ElementNode {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.Pre:
+		code := strings.Trim(getNodeText(n), "\n")
+		if r.markdown {
+			return "\n\n```\n" + code + "\n```\n\n"
+		}
+		return "\n\n" + code + "\n\n"
+	case atom.Code:
+		if r.markdown {
+			return "`" + getNodeText(n) + "`"
+		}
+		return getNodeText(n)
+	case atom.Img:
+		alt := getAttr(n, "alt")
+		if r.markdown {
+			return "![" + markdownEscaper.Replace(alt) + "](" + markdownURLEscaper.Replace(getAttr(n, "src")) + ")"
+		}
+		return alt
+	case atom.Details:
+		if !hasClass(n, spoilerClass) {
+			break
+		}
+		// Spoiler content is hidden, only its summary is shown
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.DataAtom == atom.Summary {
+				return "\n\n" + strings.TrimSpace(r.renderChildren(c)) + "\n\n"
+			}
+		}
+		return ""
+	}
+
+	inner := r.renderChildren(n)
+	switch n.DataAtom {
+	case atom.P, atom.Div, atom.Details, atom.Ul, atom.Ol, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		return "\n\n" + inner + "\n\n"
+	case atom.Summary, atom.Li:
+		prefix := ""
+		if n.DataAtom == atom.Li {
+			prefix = "- "
+		}
+		return prefix + strings.TrimSpace(inner) + "\n"
+	case atom.Blockquote:
+		if r.markdown {
+			lines := strings.Split(strings.TrimSpace(inner), "\n")
+			for i, line := range lines {
+				lines[i] = strings.TrimRight("> "+line, " ")
+			}
+			inner = strings.Join(lines, "\n")
+		}
+		return "\n\n" + inner + "\n\n"
+	case atom.A:
+		href := getAttr(n, "href")
+		switch {
+		case href == "":
+			return inner
+		case r.markdown:
+			return "[" + inner + "](" + markdownURLEscaper.Replace(href) + ")"
+		case inner == href || strings.TrimSpace(inner) == "":
+			return href
+		}
+		return inner + " (" + href + ")"
+	}
+
+	if r.markdown {
+		switch n.DataAtom {
+		case atom.B, atom.Strong:
+			return wrapMarkdown(inner, "**")
+		case atom.I, atom.Em:
+			return wrapMarkdown(inner, "_")
+		case atom.S, atom.Del:
+			return wrapMarkdown(inner, "~~")
+		}
+	}
+	return inner
+}
+
+func (r *textRenderer) renderChildren(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(r.render(c))
+	}
+	return sb.String()
+}
+
+// wrapMarkdown wraps the text with the markup keeping surrounding spaces outside, e.g. "**bold** "
+func wrapMarkdown(text, markup string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	start := strings.Index(text, trimmed)
+	return text[:start] + markup + trimmed + markup + text[start+len(trimmed):]
+}
+
+// getNodeText returns the text of the node and its children
+func getNodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(getNodeText(c))
+	}
+	return sb.String()
+}
+
+// hasClass reports whether the node has the class
+func hasClass(n *html.Node, class string) bool {
+	return slices.Contains(strings.Fields(getAttr(n, "class")), class)
+}
+
+// getAttr returns the node attribute value
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tbl := []struct {
+		inp string
+		out string
+	}{
+		{"", ""},
+		{"<p>First</p>\n<p>Second<br/>line</p>", "First\n\nSecond\nline"},
+		{`<p><b>Bold </b><i>italic</i> <s>strike</s> <u>under</u></p>`, "**Bold** _italic_ ~~strike~~ under"},
+		{`<p><a href="https://t.me/s/telegram">Telegram</a> news</p>`, "[Telegram](https://t.me/s/telegram) news"},
+		{`<p>snake_case *star* [x]</p>`, `snake\_case \*star\* \[x\]`},
+		{"<p>Run <code>go_test</code>:</p><pre>func main() {\n\treturn\n}</pre>", "Run `go_test`:\n\n```\nfunc main() {\n\treturn\n}\n```"},
+		{`<blockquote>Quote<br/>line</blockquote><p>After</p>`, "> Quote\n> line\n\nAfter"},
+		{`<p>Plot</p><details class="spoiler"><summary>Spoiler</summary>secret</details>`, "Plot\n\nSpoiler"},
+		{`<details class="spoiler">secret</details><p>After</p>`, "After"},
+		{`<details><summary>Quote</summary><blockquote>long quote</blockquote></details>`, "Quote\n\n> long quote"},
+		{`<a href="https://example.com/a (b)">link</a> <img src="https://t.me/i 1.jpg" alt="pic">`,
+			"[link](https://example.com/a%20%28b%29) ![pic](https://t.me/i%201.jpg)"},
+		{`<ul><li>one</li><li>two</li></ul><img src="https://t.me/i.jpg" alt="pic">`, "- one\n- two\n\n![pic](https://t.me/i.jpg)"},
+		{`<p><b> </b>&amp; &lt;tag&gt;</p>`, "& <tag>"},
+	}
+	for _, tb := range tbl {
+		assert.Equal(t, tb.out, HTMLToMarkdown(tb.inp))
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tbl := []struct {
+		inp string
+		out string
+	}{
+		{"", ""},
+		{"<p>First</p>\n<p>Second<br/>line</p>", "First\n\nSecond\nline"},
+		{`<p><b>Bold</b> <i>italic</i> snake_case</p>`, "Bold italic snake_case"},
+		{`<p><a href="https://t.me/s/telegram">Telegram</a> <a href="https://t.me">https://t.me</a> <a>no link</a></p>`,
+			"Telegram (https://t.me/s/telegram) https://t.me no link"},
+		{"<pre>a\n  b</pre><p>c</p>", "a\n  b\n\nc"},
+		{`<blockquote>Quote</blockquote><img src="https://t.me/i.jpg" alt="pic">`, "Quote\n\npic"},
+		{`<p>Plot</p><details class="spoiler"><summary>Spoiler</summary>secret</details>`, "Plot\n\nSpoiler"},
+		{`<details><summary>Quote</summary><blockquote>long quote</blockquote></details>`, "Quote\n\nlong quote"},
+	}
+	for _, tb := range tbl {
+		assert.Equal(t, tb.out, HTMLToText(tb.inp))
+	}
+
+	// Spoiler content is not shown in any text representation
+	const spoiler = `<details class="spoiler"><summary>Spoiler</summary>secret <b>text</b></details>`
+	assert.NotContains(t, HTMLToText(spoiler), "secret")
+	assert.NotContains(t, HTMLToMarkdown(spoiler), "secret")
+}
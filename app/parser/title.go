@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// TitleStrategy defines how the post title is extracted
+type TitleStrategy string
+
+// Supported title strategies
+const (
+	TitleFirstLine     TitleStrategy = "first-line"
+	TitleFirstSentence TitleStrategy = "first-sentence"
+	TitleFirstWords    TitleStrategy = "first-words"
+	TitleBold          TitleStrategy = "bold"
+	TitleLinkPreview   TitleStrategy = "link-preview"
+	TitleMedia         TitleStrategy = "media"
+)
+
+const defaultTitleMaxLength = 30
+const defaultTitleWords = 10
+
+// TitleOptions defines how the post title is built
+type TitleOptions struct {
+	// Strategies are tried in order until one returns non-empty title.
+	// First line is used if empty.
+	Strategies []TitleStrategy
+	// MaxLength is the title length limit in runes, 30 if not set
+	MaxLength int
+	// Words is the number of words for the first-words strategy, 10 if not set
+	Words int
+}
+
+var tagRe = regexp.MustCompile("<[^>]*>")
+var lineBreakRe = regexp.MustCompile(`(?i)<br\s*/?>|</p>|\n`)
+var sentenceEndRe = regexp.MustCompile(`[.!?…]+(\s|$)`)
+
+// ParseTitleStrategies parses comma separated list of title strategies
+func ParseTitleStrategies(str string) ([]TitleStrategy, error) {
+	var strategies []TitleStrategy
+	for _, name := range strings.Split(str, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		strategy := TitleStrategy(name)
+		switch strategy {
+		case TitleFirstLine, TitleFirstSentence, TitleFirstWords, TitleBold, TitleLinkPreview, TitleMedia:
+			strategies = append(strategies, strategy)
+		default:
+			return nil, fmt.Errorf("unknown title strategy: %s", name)
+		}
+	}
+	return strategies, nil
+}
+
+// BuildPostTitle returns the post title using the first strategy that gives non-empty result
+func BuildPostTitle(s *goquery.Selection, text string, opts TitleOptions) string {
+	strategies := opts.Strategies
+	if len(strategies) == 0 {
+		strategies = []TitleStrategy{TitleFirstLine}
+	}
+	maxLength := opts.getMaxLength()
+	words := opts.Words
+	if words <= 0 {
+		words = defaultTitleWords
+	}
+	// Collapsed spoilers and quotes are not shown in the title
+	text = removeDetails(text)
+
+	for _, strategy := range strategies {
+		var title string
+		switch strategy {
+		case TitleFirstLine:
+			title = getFirstLine(text)
+		case TitleFirstSentence:
+			title = getFirstSentence(text)
+		case TitleFirstWords:
+			title = getFirstWords(text, words)
+		case TitleBold:
+			title = getBoldHeading(text)
+		case TitleLinkPreview:
+			title = GetLinkPreviewTitle(s)
+		case TitleMedia:
+			title = GetMediaTitle(s)
+		}
+		if title != "" {
+			return ShortenText(title, maxLength)
+		}
+	}
+	return ""
+}
+
+// getMaxLength returns the title length limit or the default one
+func (o TitleOptions) getMaxLength() int {
+	if o.MaxLength <= 0 {
+		return defaultTitleMaxLength
+	}
+	return o.MaxLength
+}
+
+// GetLinkPreviewTitle returns the title of the link preview attached to the post
+func GetLinkPreviewTitle(s *goquery.Selection) string {
+	return strings.TrimSpace(s.Find(".link_preview_title").First().Text())
+}
+
+// GetMediaTitle returns the title describing the post media, e.g. "Photo album (5)" or "Sticker"
+func GetMediaTitle(s *goquery.Selection) string {
+	photos := s.Find(".tgme_widget_message_photo_wrap").Length()
+	videos := s.Find("video").FilterFunction(func(_ int, s *goquery.Selection) bool { return !isSticker(s) }).Length()
+	switch {
+	case photos+videos > 1:
+		return fmt.Sprintf("Photo album (%d)", photos+videos)
+	case photos == 1:
+		return "Photo"
+	case videos == 1:
+		return "Video"
+	case GetSticker(s) != nil:
+		return "Sticker"
+	}
+	return ""
+}
+
+// removeDetails returns the HTML string without <details> elements and their content
+func removeDetails(text string) string {
+	if !strings.Contains(text, "<details") {
+		return text
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(text))
+	if err != nil {
+		return text
+	}
+	body := doc.Find("body")
+	body.Find("details").Remove()
+	result, err := body.Html()
+	if err != nil {
+		return text
+	}
+	return result
+}
+
+// stripTags returns plain text of the HTML string
+func stripTags(text string) string {
+	return strings.TrimSpace(html.UnescapeString(tagRe.ReplaceAllString(text, "")))
+}
+
+// getFirstLine returns the first non-empty line of the post text
+func getFirstLine(text string) string {
+	for _, line := range lineBreakRe.Split(text, -1) {
+		if line = stripTags(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// getPlainText returns the post text as a single line
+func getPlainText(text string) string {
+	text = lineBreakRe.ReplaceAllString(text, " ")
+	return strings.Join(strings.Fields(stripTags(text)), " ")
+}
+
+// getFirstSentence returns the first sentence of the post text
+func getFirstSentence(text string) string {
+	text = getPlainText(text)
+	if loc := sentenceEndRe.FindStringIndex(text); loc != nil {
+		return strings.TrimRightFunc(text[:loc[1]], unicode.IsSpace)
+	}
+	return text
+}
+
+// getFirstWords returns the first n words of the post text
+func getFirstWords(text string, n int) string {
+	words := strings.Fields(getPlainText(text))
+	if len(words) <= n {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words[:n], " ") + "..."
+}
+
+// getBoldHeading returns the bold text the post starts with
+func getBoldHeading(text string) string {
+	text = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), "<p>"))
+	if !strings.HasPrefix(text, "<b>") {
+		return ""
+	}
+	end := strings.Index(text, "</b>")
+	if end == -1 {
+		return ""
+	}
+	return stripTags(text[len("<b>"):end])
+}
@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseTitleStrategies(t *testing.T) {
+	strategies, err := ParseTitleStrategies("")
+	assert.Nil(t, err)
+	assert.Nil(t, strategies)
+
+	strategies, err = ParseTitleStrategies("bold, first-sentence,media")
+	assert.Nil(t, err)
+	assert.Equal(t, []TitleStrategy{TitleBold, TitleFirstSentence, TitleMedia}, strategies)
+
+	_, err = ParseTitleStrategies("first-line,unknown")
+	assert.Equal(t, fmt.Errorf("unknown title strategy: unknown"), err)
+}
+
+func TestBuildPostTitle(t *testing.T) {
+	const text = "<p><b>Big news</b> Today we are excited to announce. Something new!</p>\n<p>Second line</p>"
+	const spoiler = "<p><details><summary>Spoiler</summary>Bruce dies</details> at the end. Really</p>"
+	s := getEmptySelection()
+
+	tbl := []struct {
+		text string
+		opts TitleOptions
+		out  string
+	}{
+		{text, TitleOptions{}, "Big news Today we are excited..."},
+		{text, TitleOptions{MaxLength: 100}, "Big news Today we are excited to announce. Something new!"},
+		{text, TitleOptions{Strategies: []TitleStrategy{TitleFirstSentence}, MaxLength: 100}, "Big news Today we are excited to announce."},
+		{text, TitleOptions{Strategies: []TitleStrategy{TitleFirstWords}, Words: 3, MaxLength: 100}, "Big news Today..."},
+		{text, TitleOptions{Strategies: []TitleStrategy{TitleBold}}, "Big news"},
+		{"<p>Plain &amp; simple</p>", TitleOptions{Strategies: []TitleStrategy{TitleBold, TitleFirstLine}}, "Plain & simple"},
+		{"", TitleOptions{Strategies: []TitleStrategy{TitleFirstLine, TitleMedia}}, ""},
+		{"<p>One two</p>", TitleOptions{Strategies: []TitleStrategy{TitleFirstWords}}, "One two"},
+		{"<p>No sentence end</p>", TitleOptions{Strategies: []TitleStrategy{TitleFirstSentence}}, "No sentence end"},
+		// Spoiler rendered as <details> is not shown in the title
+		{spoiler, TitleOptions{}, "at the end. Really"},
+		{spoiler, TitleOptions{Strategies: []TitleStrategy{TitleFirstSentence}}, "at the end."},
+		{spoiler, TitleOptions{Strategies: []TitleStrategy{TitleFirstWords}}, "at the end. Really"},
+	}
+	for _, tb := range tbl {
+		title := BuildPostTitle(s, tb.text, tb.opts)
+		assert.Equal(t, tb.out, title)
+	}
+}
+
+func TestBuildPostTitle_Media(t *testing.T) {
+	// Test post has one photo and one video
+	title := BuildPostTitle(getSelection(), "", TitleOptions{Strategies: []TitleStrategy{TitleMedia}})
+	assert.Equal(t, "Photo album (2)", title)
+
+	tbl := []struct {
+		html string
+		out  string
+	}{
+		{`<a class="tgme_widget_message_photo_wrap"></a>`, "Photo"},
+		{`<video src="video.mp4"></video>`, "Video"},
+		{`<div class="tgme_widget_message_text">Text</div>`, ""},
+		{`<div class="tgme_widget_message_videosticker"><video src="sticker.webm"></video></div>`, "Sticker"},
+	}
+	for _, tb := range tbl {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tb.html))
+		assert.Nil(t, err)
+		assert.Equal(t, tb.out, GetMediaTitle(doc.Find("body")))
+	}
+}
+
+func TestGetLinkPreviewTitle(t *testing.T) {
+	const html = `<body><a class="tgme_widget_message_link_preview" href="https://telegram.org">
+		<div class="link_preview_site_name">Telegram</div>
+		<div class="link_preview_title"> Telegram Messenger </div>
+	</a></body>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	assert.Nil(t, err)
+
+	s := doc.Find("body")
+	assert.Equal(t, "Telegram Messenger", GetLinkPreviewTitle(s))
+	assert.Equal(t, "Telegram Messenger", BuildPostTitle(s, "", TitleOptions{Strategies: []TitleStrategy{TitleFirstLine, TitleLinkPreview}}))
+
+	// No link preview
+	assert.Equal(t, "", GetLinkPreviewTitle(getEmptySelection()))
+}
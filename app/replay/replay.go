@@ -0,0 +1,99 @@
+// Package replay provides the HTTP transport recording responses to files and replaying them offline.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Mode of the transport
+type Mode string
+
+// Supported transport modes
+const (
+	// Record makes real requests and saves the responses
+	Record Mode = "record"
+	// Replay returns the saved responses without network requests
+	Replay Mode = "replay"
+)
+
+// ErrNotRecorded is returned in replay mode for the request without saved response
+var ErrNotRecorded = errors.New("response is not recorded")
+
+// Characters not safe for file names
+var unsafeNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Transport records or replays HTTP responses, one file per request in the directory.
+// Redirects are recorded as separate responses and followed by the client on replay.
+type Transport struct {
+	Dir  string
+	Mode Mode
+	// Base makes the real requests in record mode, http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+// NewClient returns HTTP client recording or replaying the responses in the directory
+func NewClient(dir string, mode Mode) *http.Client {
+	return &http.Client{Transport: &Transport{Dir: dir, Mode: mode}}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Mode == Replay {
+		return Load(t.Dir, req)
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	res, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := Save(t.Dir, res); err != nil {
+		res.Body.Close() // nolint
+		return nil, fmt.Errorf("can't record %s: %w", req.URL, err)
+	}
+	return res, nil
+}
+
+// FileName returns the file name of the response to the request: readable URL part and the hash of the full URL
+func FileName(req *http.Request) string {
+	name := strings.Trim(unsafeNameRe.ReplaceAllString(req.URL.Host+req.URL.Path, "_"), "_")
+	hash := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return name + "-" + hex.EncodeToString(hash[:4]) + ".http"
+}
+
+// Save writes the response status, headers and body to the directory, response body is kept readable
+func Save(dir string, res *http.Response) error {
+	data, err := httputil.DumpResponse(res, true)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, FileName(res.Request)), data, 0o600)
+}
+
+// Load reads the saved response to the request from the directory
+func Load(dir string, req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName(req))) //nolint:gosec // tolerable security risk
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, req.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+}
@@ -0,0 +1,86 @@
+package replay
+
+import (
+	"compress/gzip"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/s/renamed":
+			http.Redirect(w, r, "/renamed", http.StatusFound)
+		case "/renamed":
+			_, _ = w.Write([]byte("channel info"))
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			_, _ = gz.Write([]byte("compressed page"))
+			_ = gz.Close()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	get := func(client *http.Client, path string) (string, int, string) {
+		res, err := client.Get(ts.URL + path)
+		assert.Nil(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		assert.Nil(t, err)
+		return string(body), res.StatusCode, res.Request.URL.Path
+	}
+
+	dir := t.TempDir()
+	recorder := NewClient(dir, Record)
+	body, status, finalPath := get(recorder, "/s/renamed")
+	assert.Equal(t, "channel info", body)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "/renamed", finalPath)
+	body, _, _ = get(recorder, "/gzip")
+	assert.Equal(t, "compressed page", body)
+	_, status, _ = get(recorder, "/missing")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// Redirect is saved as separate response
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 4)
+
+	// Replay works without the server
+	ts.Close()
+	player := NewClient(dir, Replay)
+	body, status, finalPath = get(player, "/s/renamed")
+	assert.Equal(t, "channel info", body)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "/renamed", finalPath)
+	body, _, _ = get(player, "/gzip")
+	assert.Equal(t, "compressed page", body)
+	_, status, _ = get(player, "/missing")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	_, err = player.Get(ts.URL + "/other")
+	assert.True(t, errors.Is(err, ErrNotRecorded))
+}
+
+func TestFileName(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://t.me/s/telegram?before=10", http.NoBody)
+	assert.Nil(t, err)
+	name := FileName(req)
+	assert.True(t, strings.HasPrefix(name, "t.me_s_telegram-"), name)
+	assert.True(t, strings.HasSuffix(name, ".http"), name)
+
+	// Query is a part of the hash
+	other, err := http.NewRequest(http.MethodGet, "https://t.me/s/telegram?before=20", http.NoBody)
+	assert.Nil(t, err)
+	assert.NotEqual(t, name, FileName(other))
+	assert.Equal(t, filepath.Base(name), name)
+}